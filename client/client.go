@@ -1 +1,136 @@
+// Package client connects to doorman servers.
 package client
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/notfresh/zxdoorman/proto"
+	"github.com/notfresh/zxdoorman/tlsconfig"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// Client is a connection to a doorman server.
+type Client struct {
+	proto.CapacityClient
+	conn *grpc.ClientConn
+}
+
+type options struct {
+	tls         bool
+	caFile      string
+	certFile    string
+	keyFile     string
+	serverName  string
+	token       string
+	dialTimeout time.Duration
+}
+
+// Option configures a Client created with New.
+type Option func(*options)
+
+// WithTLS makes the client connect using TLS, verifying the server's
+// certificate against the CAs in caFile, or the system's CAs if it
+// is empty.
+func WithTLS(caFile string) Option {
+	return func(opts *options) {
+		opts.tls = true
+		opts.caFile = caFile
+	}
+}
+
+// WithClientCertificate makes the client present the certificate in
+// certFile, with the key in keyFile, to servers verifying client
+// certificates. The files are reloaded when they change. It implies
+// WithTLS.
+func WithClientCertificate(certFile, keyFile string) Option {
+	return func(opts *options) {
+		opts.tls = true
+		opts.certFile = certFile
+		opts.keyFile = keyFile
+	}
+}
+
+// WithServerName overrides the name expected in the server's
+// certificate, which is by default the host in the address.
+func WithServerName(name string) Option {
+	return func(opts *options) {
+		opts.serverName = name
+	}
+}
+
+// WithToken makes the client authenticate with token, sent as a
+// bearer token with every request. It requires WithTLS, so that the
+// token is never sent in clear.
+func WithToken(token string) Option {
+	return func(opts *options) {
+		opts.token = token
+	}
+}
+
+// WithDialTimeout makes New wait for the connection to be established
+// for at most timeout.
+func WithDialTimeout(timeout time.Duration) Option {
+	return func(opts *options) {
+		opts.dialTimeout = timeout
+	}
+}
+
+// New returns a client of the doorman server at addr.
+func New(addr string, opts ...Option) (*Client, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.token != "" && !o.tls {
+		return nil, errors.New("client: a token requires TLS")
+	}
+
+	creds := insecure.NewCredentials()
+	if o.tls {
+		config, err := tlsconfig.Client(o.caFile, o.certFile, o.keyFile, o.serverName)
+		if err != nil {
+			return nil, err
+		}
+		creds = credentials.NewTLS(config)
+	}
+
+	ctx := context.Background()
+	dialOpts := []grpc.DialOption{grpc.WithTransportCredentials(creds)}
+	if o.token != "" {
+		dialOpts = append(dialOpts, grpc.WithPerRPCCredentials(tokenCredentials{o.token}))
+	}
+	if o.dialTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.dialTimeout)
+		defer cancel()
+		dialOpts = append(dialOpts, grpc.WithBlock())
+	}
+	conn, err := grpc.DialContext(ctx, addr, dialOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{CapacityClient: proto.NewCapacityClient(conn), conn: conn}, nil
+}
+
+// Close closes the connection to the server.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// tokenCredentials sends a bearer token with every request, over
+// TLS connections only.
+type tokenCredentials struct {
+	token string
+}
+
+func (c tokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + c.token}, nil
+}
+
+func (c tokenCredentials) RequireTransportSecurity() bool {
+	return true
+}
@@ -0,0 +1,248 @@
+package client
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/notfresh/zxdoorman/proto"
+	doorman "github.com/notfresh/zxdoorman/server"
+	"github.com/notfresh/zxdoorman/tlsconfig"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+)
+
+// authority is a test certificate authority.
+type authority struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	dir  string
+	// writes counts the files written.
+	writes int
+}
+
+func newAuthority(t *testing.T, dir string) *authority {
+	t.Helper()
+	ca := &authority{dir: dir}
+	ca.cert, ca.key = ca.sign(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "test CA"},
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}, "ca")
+	return ca
+}
+
+// sign signs template, or self-signs it if the authority has no
+// certificate yet, and writes the certificate and its key to
+// name.pem and name.key.
+func (ca *authority) sign(t *testing.T, template *x509.Certificate, name string) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		t.Fatal(err)
+	}
+	template.SerialNumber = serial
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+	parent, parentKey := template, key
+	if ca.cert != nil {
+		parent, parentKey = ca.cert, ca.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca.write(t, name+".pem", "CERTIFICATE", der)
+	ca.write(t, name+".key", "EC PRIVATE KEY", keyDER)
+	return cert, key
+}
+
+func (ca *authority) write(t *testing.T, name, kind string, der []byte) {
+	t.Helper()
+	path := filepath.Join(ca.dir, name)
+	data := pem.EncodeToMemory(&pem.Block{Type: kind, Bytes: der})
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	// Makes sure the change is noticed even if the file system's
+	// timestamps are coarse.
+	ca.writes++
+	future := time.Now().Add(time.Duration(ca.writes) * time.Second)
+	if err := os.Chtimes(path, future, future); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (ca *authority) path(name string) string {
+	return filepath.Join(ca.dir, name)
+}
+
+func (ca *authority) serverCert(t *testing.T) *x509.Certificate {
+	cert, _ := ca.sign(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "doorman"},
+		DNSNames:    []string{"localhost"},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, "server")
+	return cert
+}
+
+func (ca *authority) clientCert(t *testing.T, name string) {
+	ca.sign(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: name},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, name)
+}
+
+func TestMutualTLS(t *testing.T) {
+	ca := newAuthority(t, t.TempDir())
+	first := ca.serverCert(t)
+	ca.clientCert(t, "client")
+
+	config, err := tlsconfig.Server(ca.path("server.pem"), ca.path("server.key"), ca.path("ca.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dm, err := doorman.MakeTestServer(&proto.ResourcePB{
+		IdentifierGlob: "*",
+		Capacity:       100,
+		Algo:           &proto.AlgorithmPB{LeaseLength: 60, RefreshInterval: 10},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dm.Close()
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	unary, stream := doorman.IdentityInterceptors(doorman.CertificateIdentity)
+	rpcServer := grpc.NewServer(grpc.Creds(credentials.NewTLS(config)), grpc.UnaryInterceptor(unary), grpc.StreamInterceptor(stream))
+	proto.RegisterCapacityServer(rpcServer, dm)
+	go rpcServer.Serve(lis)
+	defer rpcServer.Stop()
+	addr := lis.Addr().String()
+
+	getCapacity := func(clientID string, opts ...Option) error {
+		c, err := New(addr, opts...)
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_, err = c.GetCapacity(ctx, &proto.GetCapacityRequest{
+			ClientId: clientID,
+			Resource: []*proto.GetCapacityRequest_ResourceRequest{{ResourceId: "res", Want: 10}},
+		})
+		return err
+	}
+
+	withCert := []Option{WithTLS(ca.path("ca.pem")), WithClientCertificate(ca.path("client.pem"), ca.path("client.key"))}
+	if err := getCapacity("client", withCert...); err != nil {
+		t.Errorf("with a client certificate: %v", err)
+	}
+	if err := getCapacity("other", withCert...); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("as another client: %v, want PermissionDenied", err)
+	}
+	if err := getCapacity("client", WithTLS(ca.path("ca.pem"))); err == nil {
+		t.Errorf("without a client certificate: no error")
+	}
+	if err := getCapacity("client"); err == nil {
+		t.Errorf("without TLS: no error")
+	}
+
+	// The server presents the new certificate once it is rewritten.
+	second := ca.serverCert(t)
+	serverCert := func() *big.Int {
+		clientConfig, err := tlsconfig.Client(ca.path("ca.pem"), ca.path("client.pem"), ca.path("client.key"), "localhost")
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn, err := tls.Dial("tcp", addr, clientConfig)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		return conn.ConnectionState().PeerCertificates[0].SerialNumber
+	}
+	if got := serverCert(); got.Cmp(second.SerialNumber) != 0 {
+		t.Errorf("server presented certificate %v, want %v (the first one was %v)", got, second.SerialNumber, first.SerialNumber)
+	}
+}
+
+func TestToken(t *testing.T) {
+	ca := newAuthority(t, t.TempDir())
+	ca.serverCert(t)
+
+	config, err := tlsconfig.Server(ca.path("server.pem"), ca.path("server.key"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dm, err := doorman.MakeTestServer(&proto.ResourcePB{
+		IdentifierGlob: "*",
+		Capacity:       100,
+		Algo:           &proto.AlgorithmPB{LeaseLength: 60, RefreshInterval: 10},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dm.Close()
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	unary, stream := doorman.IdentityInterceptors(doorman.TokenIdentity(map[string]string{"secret": "client"}))
+	rpcServer := grpc.NewServer(grpc.Creds(credentials.NewTLS(config)), grpc.UnaryInterceptor(unary), grpc.StreamInterceptor(stream))
+	proto.RegisterCapacityServer(rpcServer, dm)
+	go rpcServer.Serve(lis)
+	defer rpcServer.Stop()
+	addr := lis.Addr().String()
+
+	c, err := New(addr, WithTLS(ca.path("ca.pem")), WithToken("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := c.GetCapacity(ctx, &proto.GetCapacityRequest{
+		ClientId: "client",
+		Resource: []*proto.GetCapacityRequest_ResourceRequest{{ResourceId: "res", Want: 10}},
+	}); err != nil {
+		t.Errorf("with a token over TLS: %v", err)
+	}
+
+	// The token is never sent in clear.
+	if c, err := New(addr, WithToken("secret")); err == nil {
+		c.Close()
+		t.Errorf("New with a token but without TLS: no error")
+	}
+}
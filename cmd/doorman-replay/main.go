@@ -0,0 +1,138 @@
+// doorman-replay replays GetCapacity requests recorded by a server
+// started with --record_requests against an in-process server with
+// the given configuration. Time is simulated, so hours of traffic
+// replay in seconds. It writes a CSV line for every lease granted,
+// which shows how each client's capacity evolves over time under the
+// configuration.
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/notfresh/zxdoorman/configuration"
+	"github.com/notfresh/zxdoorman/proto"
+	doorman "github.com/notfresh/zxdoorman/server"
+)
+
+var (
+	config = flag.String("config", "", "source to load the config from, as for the server")
+	input  = flag.String("input", "", "recorded requests to replay (- for stdin)")
+	output = flag.String("output", "-", "file to write the CSV report to (- for stdout)")
+	speed  = flag.Float64("speed", 0, "replay speed relative to the recording, 0 for as fast as possible")
+)
+
+// replayer feeds recorded requests to a server. The server is created
+// at the time of the first request, so it goes through learning mode
+// like a freshly started server would.
+type replayer struct {
+	config *proto.ResourceRepository
+	clock  *doorman.ManualClock
+	server *doorman.Server
+	start  time.Time
+	last   time.Time
+	out    *csv.Writer
+}
+
+func (r *replayer) replay(when time.Time, in *proto.GetCapacityRequest) error {
+	if r.server == nil {
+		r.clock = doorman.NewManualClock(when)
+		server, err := doorman.NewServer(context.Background(), "replay", doorman.WithClock(r.clock))
+		if err != nil {
+			return err
+		}
+		if err := server.LoadConfig(context.Background(), r.config, nil); err != nil {
+			return err
+		}
+		r.server, r.start, r.last = server, when, when
+	}
+
+	if *speed > 0 && when.After(r.last) {
+		time.Sleep(time.Duration(float64(when.Sub(r.last)) / *speed))
+	}
+	if when.After(r.last) {
+		r.last = when
+	}
+	r.clock.Set(when)
+
+	out, err := r.server.GetCapacity(context.Background(), in)
+	if err != nil {
+		// The configuration replayed may well refuse requests the
+		// recording server accepted.
+		log.Printf("request of %v at %v: %v", in.GetClientId(), when.Format(time.RFC3339Nano), err)
+		return nil
+	}
+	wants := make(map[string]int32)
+	for _, req := range in.GetResource() {
+		wants[req.GetResourceId()] = req.GetWant()
+	}
+	for _, resp := range out.GetResponse() {
+		if err := r.out.Write([]string{
+			r.clock.Now().Format(time.RFC3339Nano),
+			strconv.FormatFloat(r.clock.Now().Sub(r.start).Seconds(), 'f', 3, 64),
+			in.GetClientId(),
+			resp.GetResourceId(),
+			strconv.Itoa(int(wants[resp.GetResourceId()])),
+			strconv.Itoa(int(resp.GetGets().GetCapacity())),
+			strconv.Itoa(int(resp.GetSafeCapacity())),
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func main() {
+	flag.Parse()
+	if *config == "" || *input == "" {
+		fmt.Fprintln(os.Stderr, "usage: doorman-replay --config=source --input=recording [--speed=factor] [--output=file]")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	repo, err := configuration.Load(ctx, *config, configuration.Options{})
+	cancel()
+	if err != nil {
+		log.Fatalf("cannot load config: %v", err)
+	}
+
+	var in io.Reader = os.Stdin
+	if *input != "-" {
+		f, err := os.Open(*input)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		defer f.Close()
+		in = f
+	}
+	var w io.Writer = os.Stdout
+	if *output != "-" {
+		f, err := os.Create(*output)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	r := &replayer{config: repo, out: csv.NewWriter(w)}
+	r.out.Write([]string{"time", "elapsed_seconds", "client_id", "resource_id", "want", "granted", "safe_capacity"})
+	if err := doorman.ReadRecording(in, r.replay); err != nil {
+		log.Fatalf("replay failed: %v", err)
+	}
+	r.out.Flush()
+	if err := r.out.Error(); err != nil {
+		log.Fatalln(err)
+	}
+	if r.server != nil {
+		r.server.Close()
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/notfresh/zxdoorman/proto"
+	doorman "github.com/notfresh/zxdoorman/server"
+)
+
+func TestReplay(t *testing.T) {
+	start := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	var recording bytes.Buffer
+	recorder := doorman.NewRecorder(&recording)
+	for i, client := range []string{"a", "denied", "b", "a"} {
+		if err := recorder.Record(start.Add(time.Duration(i)*time.Second), &proto.GetCapacityRequest{
+			ClientId: client,
+			Resource: []*proto.GetCapacityRequest_ResourceRequest{{ResourceId: "res", Want: 30}},
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The replayed configuration refuses one of the clients: its
+	// request is skipped and the replay goes on.
+	var buf bytes.Buffer
+	r := &replayer{
+		config: &proto.ResourceRepository{Resources: []*proto.ResourcePB{{
+			IdentifierGlob: "*",
+			Capacity:       100,
+			Algo:           &proto.AlgorithmPB{LeaseLength: 60},
+			AllowedClients: []string{"a", "b"},
+		}}},
+		out: csv.NewWriter(&buf),
+	}
+	if err := doorman.ReadRecording(&recording, r.replay); err != nil {
+		t.Fatal(err)
+	}
+	r.out.Flush()
+	r.server.Close()
+
+	records, err := csv.NewReader(strings.NewReader(buf.String())).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, rec := range records {
+		got = append(got, strings.Join(rec[1:5], ","))
+	}
+	want := []string{"0.000,a,res,30", "2.000,b,res,30", "3.000,a,res,30"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("replayed %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,256 @@
+// doorman-sim simulates the clients of a resource against an
+// in-process server running the real allocation code with a
+// simulated clock, to compare configurations and algorithms before
+// rolling them out. The clients are described by a scenario file:
+//
+//	resource: db-writes
+//	duration: 10m
+//	step: 1s
+//	clients:
+//	  - id: batch
+//	    arrive: 0s
+//	    depart: 8m
+//	    want:
+//	      - {at: 0s, want: 500}
+//	      - {at: 5m, want: 100}
+//	  - id: frontend
+//	    arrive: 1m
+//	    refresh: 2s
+//	    want:
+//	      - {at: 0s, want: 300}
+//
+// Want curves are steps, relative to the start of the simulation.
+// Clients refresh at the interval the server tells them unless they
+// set their own, and stop refreshing when they depart, so their
+// lease lingers until it expires. The output is a CSV time series
+// with one column per client lease plus the total granted and the
+// resource capacity.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/notfresh/zxdoorman/configuration"
+	"github.com/notfresh/zxdoorman/proto"
+	doorman "github.com/notfresh/zxdoorman/server"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	config    = flag.String("config", "", "source to load the resource config from, as for the server")
+	scenario  = flag.String("scenario", "", "YAML file describing the simulated clients")
+	output    = flag.String("output", "-", "file to write the CSV time series to (- for stdout)")
+	algorithm = flag.String("algorithm", "", "if set, algorithm kind used by every resource instead of the configured one")
+)
+
+// duration is a time.Duration written as a string such as 1m30s.
+type duration time.Duration
+
+func (d *duration) UnmarshalYAML(value *yaml.Node) error {
+	parsed, err := time.ParseDuration(value.Value)
+	if err != nil {
+		return err
+	}
+	*d = duration(parsed)
+	return nil
+}
+
+type wantPoint struct {
+	At   duration `yaml:"at"`
+	Want int32    `yaml:"want"`
+}
+
+type simClient struct {
+	ID      string      `yaml:"id"`
+	Arrive  duration    `yaml:"arrive"`
+	Depart  duration    `yaml:"depart"`
+	Refresh duration    `yaml:"refresh"`
+	Want    []wantPoint `yaml:"want"`
+
+	lease       *proto.Lease
+	nextRefresh time.Duration
+}
+
+// wantAt returns what the client wants at offset t.
+func (c *simClient) wantAt(t time.Duration) int32 {
+	var want int32
+	for _, p := range c.Want {
+		if time.Duration(p.At) <= t {
+			want = p.Want
+		}
+	}
+	return want
+}
+
+func (c *simClient) active(t time.Duration) bool {
+	return t >= time.Duration(c.Arrive) && (c.Depart == 0 || t < time.Duration(c.Depart))
+}
+
+// has returns the capacity of the client's lease at now, 0 if it
+// has expired.
+func (c *simClient) has(now time.Time) int32 {
+	if c.lease == nil || now.Unix() >= c.lease.GetExpiryTime() {
+		return 0
+	}
+	return c.lease.GetCapacity()
+}
+
+type simScenario struct {
+	Resource string       `yaml:"resource"`
+	Duration duration     `yaml:"duration"`
+	Step     duration     `yaml:"step"`
+	Clients  []*simClient `yaml:"clients"`
+}
+
+func loadScenario(path string) (*simScenario, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s := &simScenario{Resource: "resource", Step: duration(time.Second)}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(s); err != nil {
+		return nil, err
+	}
+	if s.Duration <= 0 || s.Step <= 0 {
+		return nil, fmt.Errorf("duration and step must be positive")
+	}
+	return s, nil
+}
+
+func simulate(s *simScenario, repo *proto.ResourceRepository, w io.Writer) error {
+	start := time.Unix(0, 0).UTC()
+	clock := doorman.NewManualClock(start)
+	server, err := doorman.NewServer(context.Background(), "sim", doorman.WithClock(clock))
+	if err != nil {
+		return err
+	}
+	defer server.Close()
+	if err := server.LoadConfig(context.Background(), repo, nil); err != nil {
+		return err
+	}
+
+	out := csv.NewWriter(w)
+	header := []string{"elapsed_seconds"}
+	for _, c := range s.Clients {
+		header = append(header, c.ID)
+	}
+	out.Write(append(header, "total_granted", "capacity"))
+
+	for t := time.Duration(0); t <= time.Duration(s.Duration); t += time.Duration(s.Step) {
+		clock.Set(start.Add(t))
+		now := clock.Now()
+
+		for _, c := range s.Clients {
+			if !c.active(t) || t < c.nextRefresh {
+				continue
+			}
+			out, err := server.GetCapacity(context.Background(), &proto.GetCapacityRequest{
+				ClientId: c.ID,
+				Resource: []*proto.GetCapacityRequest_ResourceRequest{{
+					ResourceId: s.Resource,
+					Has:        &proto.Lease{Capacity: c.has(now)},
+					Want:       c.wantAt(t),
+				}},
+			})
+			if err != nil {
+				return err
+			}
+			c.lease = out.GetResponse()[0].GetGets()
+			refresh := time.Duration(c.Refresh)
+			if refresh <= 0 {
+				refresh = time.Duration(c.lease.GetRefreshInterval()) * time.Second
+			}
+			if refresh <= 0 {
+				refresh = time.Duration(s.Step)
+			}
+			c.nextRefresh = t + refresh
+		}
+
+		row := []string{strconv.FormatFloat(t.Seconds(), 'f', -1, 64)}
+		var total int32
+		for _, c := range s.Clients {
+			has := c.has(now)
+			total += has
+			row = append(row, strconv.Itoa(int(has)))
+		}
+		capacity := 0
+		for _, res := range server.Status().Resources {
+			if res.ID == s.Resource {
+				capacity = res.Capacity
+			}
+		}
+		row = append(row, strconv.Itoa(int(total)), strconv.Itoa(capacity))
+		if err := out.Write(row); err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// overrideAlgorithm makes every resource of repo, those of its
+// namespaces included, use the algorithm kind.
+func overrideAlgorithm(repo *proto.ResourceRepository, kind proto.AlgorithmPB_Kind) {
+	resources := repo.GetResources()
+	for _, ns := range repo.GetNamespaces() {
+		resources = append(resources[:len(resources):len(resources)], ns.GetResources()...)
+	}
+	for _, res := range resources {
+		if res.Algo == nil {
+			res.Algo = new(proto.AlgorithmPB)
+		}
+		res.Algo.Kind = kind
+	}
+}
+
+func main() {
+	flag.Parse()
+	if *config == "" || *scenario == "" {
+		fmt.Fprintln(os.Stderr, "usage: doorman-sim --config=source --scenario=file [--algorithm=kind] [--output=file]")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	repo, err := configuration.Load(ctx, *config, configuration.Options{})
+	cancel()
+	if err != nil {
+		log.Fatalf("cannot load config: %v", err)
+	}
+	if *algorithm != "" {
+		kind, ok := proto.AlgorithmPB_Kind_value[*algorithm]
+		if !ok {
+			log.Fatalf("unknown algorithm %q", *algorithm)
+		}
+		overrideAlgorithm(repo, proto.AlgorithmPB_Kind(kind))
+	}
+	s, err := loadScenario(*scenario)
+	if err != nil {
+		log.Fatalf("cannot load scenario: %v", err)
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "-" {
+		f, err := os.Create(*output)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := simulate(s, repo, w); err != nil {
+		log.Fatalf("simulation failed: %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/notfresh/zxdoorman/proto"
+)
+
+const testScenario = `
+resource: db
+duration: 90s
+step: 30s
+clients:
+  - id: a
+    want:
+      - {at: 0s, want: 80}
+  - id: b
+    arrive: 30s
+    depart: 60s
+    want:
+      - {at: 0s, want: 80}
+`
+
+func TestSimulate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scenario.yaml")
+	if err := ioutil.WriteFile(path, []byte(testScenario), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := loadScenario(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := &proto.ResourceRepository{Resources: []*proto.ResourcePB{{
+		IdentifierGlob: "*",
+		Capacity:       100,
+		Algo:           &proto.AlgorithmPB{Kind: proto.AlgorithmPB_FAIR, LeaseLength: 40, RefreshInterval: 30, LearningModeLength: -1},
+	}}}
+
+	var buf bytes.Buffer
+	if err := simulate(s, repo, &buf); err != nil {
+		t.Fatal(err)
+	}
+	// b shares the capacity with a while it is there, and its lease
+	// lingers after it departs until it expires.
+	want := strings.Join([]string{
+		"elapsed_seconds,a,b,total_granted,capacity",
+		"0,80,0,80,100",
+		"30,80,20,100,100",
+		"60,50,20,70,100",
+		"90,80,0,80,100",
+	}, "\n") + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("simulate wrote\n%v\nwant\n%v", got, want)
+	}
+}
+
+func TestOverrideAlgorithm(t *testing.T) {
+	repo := &proto.ResourceRepository{
+		Resources: []*proto.ResourcePB{{IdentifierGlob: "a"}},
+		Namespaces: []*proto.NamespacePB{{
+			Name:      "ns",
+			Resources: []*proto.ResourcePB{{IdentifierGlob: "b", Algo: &proto.AlgorithmPB{LeaseLength: 60}}},
+		}},
+	}
+	overrideAlgorithm(repo, proto.AlgorithmPB_FAIR)
+	for _, res := range []*proto.ResourcePB{repo.Resources[0], repo.Namespaces[0].Resources[0]} {
+		if kind := res.GetAlgo().GetKind(); kind != proto.AlgorithmPB_FAIR {
+			t.Errorf("%v uses %v, want FAIR", res.GetIdentifierGlob(), kind)
+		}
+	}
+	if got := repo.Namespaces[0].Resources[0].GetAlgo().GetLeaseLength(); got != 60 {
+		t.Errorf("lease length %v after the override, want 60", got)
+	}
+}
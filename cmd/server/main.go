@@ -1,4 +1,4 @@
-package server
+package main
 
 import (
 	"context"
@@ -7,11 +7,15 @@ import (
 	"github.com/notfresh/zxdoorman/configuration"
 	"github.com/notfresh/zxdoorman/proto"
 	doorman "github.com/notfresh/zxdoorman/server"
+	"github.com/notfresh/zxdoorman/tlsconfig"
 	"google.golang.org/grpc"
-	"gopkg.in/yaml.v3"
+	"google.golang.org/grpc/credentials"
+	"io/ioutil"
 	"log"
 	"net"
+	"net/http"
 	"os"
+	"strings"
 	"time"
 )
 
@@ -21,17 +25,31 @@ var (
 	serverRole = flag.String("server_role", "root", "Role of this server in the server tree")
 	parent     = flag.String("parent", "", "Address of the parent server which this server connects to")
 	hostname   = flag.String("hostname", "", "Use this as the hostname (if empty, use whatever the kernel reports")
-	config     = flag.String("config", "", "source to load the config from (text protobufs)")
+	config     = flag.String("config", "", "source to load the config from (YAML, JSON or text protobufs): file:path, dir:path, http(s)://url or kv:key, with optional ?poll=interval&format=format")
+
+	decisionLog           = flag.String("decision_log", "", "file to append a JSON line per sampled capacity decision to (- for stderr, empty to disable)")
+	decisionLogSampleRate = flag.Float64("decision_log_sample_rate", 0.01, "fraction of the capacity decisions written to --decision_log")
+	decisionLogVerbose    = flag.String("decision_log_verbose", "", "comma separated globs of resources whose every decision is logged, with the resource totals")
+
+	recordRequests = flag.String("record_requests", "", "file to append the received GetCapacity requests to, for doorman-replay")
+
+	configHistory = flag.Int("config_history", 10, "number of loaded configs kept for rollbacks through the admin service")
+
+	resourceIdleTime = flag.Duration("resource_idle_time", time.Minute, "how long a resource without leases goes unrequested before it is forgotten, 0 to never forget resources")
 
 	rpcDialTimeout = flag.Duration("doorman_rpc_dial_timeout", 5*time.Second, "timeout to use for connecting to the doorman server")
 
 	minimumRefreshInterval = flag.Duration("doorman_minimum_refresh_interval", 5*time.Second, "minimum refresh interval")
 
-	tls      = flag.Bool("tls", false, "Connection uses TLS if true, else plain TCP")
-	certFile = flag.String("cert_file", "", "The TLS cert file")
-	keyFile  = flag.String("key_file", "", "The TLS key file")
+	tls          = flag.Bool("tls", false, "Connection uses TLS if true, else plain TCP")
+	certFile     = flag.String("cert_file", "", "The TLS cert file, reloaded when it changes")
+	keyFile      = flag.String("key_file", "", "The TLS key file, reloaded when it changes")
+	clientCAFile = flag.String("client_ca_file", "", "CA bundle to verify client certificates against (empty to not require client certificates), reloaded when it changes")
+
+	identity       = flag.String("identity", "", "how client ids are bound to the peer: empty to trust them, cert for the common name of the client certificate (requires --client_ca_file) or token for --identity_tokens")
+	identityTokens = flag.String("identity_tokens", "", "file with a client id and its bearer token per line, for --identity=token")
 
-	etcdEndpoints      = flag.String("etcd_endpoints", "", "comma separated list of etcd endpoints")
+	etcdEndpoints      = flag.String("etcd_endpoints", "", "comma separated list of etcd endpoints used by kv: config sources")
 	masterDelay        = flag.Duration("master_delay", 10*time.Second, "delay in master elections")
 	masterElectionLock = flag.String("master_election_lock", "", "etcd path for the master election or empty for no master election")
 )
@@ -48,47 +66,142 @@ func getServerID(port int) string {
 	return fmt.Sprintf("%s:%d", hn, port)
 }
 
+// identifier returns the doorman.Identifier for the --identity mode,
+// reading the tokens of the token mode from tokensFile.
+func identifier(mode, tokensFile string) (doorman.Identifier, error) {
+	switch mode {
+	case "cert":
+		if !*tls || *clientCAFile == "" {
+			return nil, fmt.Errorf("--identity=cert requires --tls and --client_ca_file")
+		}
+		return doorman.CertificateIdentity, nil
+	case "token":
+		data, err := ioutil.ReadFile(tokensFile)
+		if err != nil {
+			return nil, err
+		}
+		tokens := make(map[string]string)
+		for i, line := range strings.Split(string(data), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+				continue
+			}
+			if len(fields) != 2 {
+				return nil, fmt.Errorf("%v:%d: want a client id and a token", tokensFile, i+1)
+			}
+			tokens[fields[1]] = fields[0]
+		}
+		return doorman.TokenIdentity(tokens), nil
+	}
+	return nil, fmt.Errorf("unknown identity mode %q", mode)
+}
+
 func main() {
+	flag.Parse()
 	if *config == "" {
 		log.Fatalln("--config cannot be empty")
 	}
+	if *configHistory < 1 {
+		log.Fatalln("--config_history must be at least 1, for the current config")
+	}
 
-	var cfg configuration.SourceFunc
-	kind, path := configuration.ParseSource(*config)
-	switch {
-	case kind == "file":
-		cfg = configuration.LocalFile(path)
-	default:
-		log.Fatalln("Fail to Parse config")
+	src, err := configuration.ParseSource(*config)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	var endpoints []string
+	if *etcdEndpoints != "" {
+		endpoints = strings.Split(*etcdEndpoints, ",")
+	}
+	cfg, err := configuration.Open(src, configuration.Options{
+		EtcdEndpoints: endpoints,
+		DialTimeout:   *rpcDialTimeout,
+	})
+	if err != nil {
+		log.Fatalf("cannot open config %v: %v\n", src, err)
 	}
 	// zx:构建一个服务器实例
-	dm, err := doorman.NewServer(context.Background(), getServerID(*port))
+	opts := []doorman.ServerOption{doorman.WithConfigHistory(*configHistory), doorman.WithResourceIdleTime(*resourceIdleTime)}
+	if *decisionLog != "" {
+		w := os.Stderr
+		if *decisionLog != "-" {
+			if w, err = os.OpenFile(*decisionLog, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644); err != nil {
+				log.Fatalf("cannot open decision log: %v\n", err)
+			}
+		}
+		var verbose []string
+		if *decisionLogVerbose != "" {
+			verbose = strings.Split(*decisionLogVerbose, ",")
+		}
+		opts = append(opts, doorman.WithDecisionLogger(doorman.NewDecisionLogger(w, *decisionLogSampleRate, verbose)))
+	}
+	if *recordRequests != "" {
+		w, err := os.OpenFile(*recordRequests, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			log.Fatalf("cannot open request recording: %v\n", err)
+		}
+		opts = append(opts, doorman.WithRecorder(doorman.NewRecorder(w)))
+	}
+	dm, err := doorman.NewServer(context.Background(), getServerID(*port), opts...)
 	if err != nil {
 		log.Fatalf("doorman.NewIntermediate: %v\n", err)
 	}
 
-	rpcServer := grpc.NewServer() // zx what's this? the server is a business unrelated server
+	var serverOpts []grpc.ServerOption
+	if *tls {
+		config, err := tlsconfig.Server(*certFile, *keyFile, *clientCAFile)
+		if err != nil {
+			log.Fatalf("cannot load TLS credentials: %v\n", err)
+		}
+		serverOpts = append(serverOpts, grpc.Creds(credentials.NewTLS(config)))
+	}
+	if *identity != "" {
+		identify, err := identifier(*identity, *identityTokens)
+		if err != nil {
+			log.Fatalf("cannot set up client identities: %v\n", err)
+		}
+		unary, stream := doorman.IdentityInterceptors(identify)
+		serverOpts = append(serverOpts, grpc.UnaryInterceptor(unary), grpc.StreamInterceptor(stream))
+	}
+	rpcServer := grpc.NewServer(serverOpts...) // zx what's this? the server is a business unrelated server
 	proto.RegisterCapacityServer(rpcServer, dm)
+	proto.RegisterAdminServer(rpcServer, dm)
 
 	go func() {
+		configured := false
 		for {
 			data, err := cfg(context.Background())
 			if err != nil {
-				log.Fatalln("Fail to Parse config", err)
+				// A running server keeps its configuration through a
+				// failed fetch; the source tries again on its own.
+				if !configured {
+					log.Fatalln("Fail to read config", err)
+				}
+				log.Println("Fail to read config, keeping the current one:", err)
+				dm.ConfigLoadFailed()
+				continue
 			}
-			resRepo := new(proto.ResourceRepository)
-			if err = yaml.Unmarshal(data, resRepo); err != nil {
+			resRepo, err := configuration.Decode(data, src.ConfigFormat())
+			if err != nil {
 				log.Println("Fail to parse config", err)
+				dm.ConfigLoadFailed()
 				continue
 			}
 
 			// zx:表示doorman, 现在开始加载配置
 			if err := dm.LoadConfig(context.Background(), resRepo, map[string]*time.Time{}); err != nil {
-				log.Fatalf("cannot load config: %v\n", err)
+				log.Printf("cannot load config: %v\n", err)
+				continue
 			}
+			configured = true
+		}
+	}()
+	go func() {
+		log.Printf("Debug server listening on port %v", *debugPort)
+		if err := http.ListenAndServe(fmt.Sprintf(":%d", *debugPort), dm.DebugHandler()); err != nil {
+			log.Printf("debug server: %v", err)
 		}
 	}()
-	log.Println(fmt.Sprintf("Server listen on port %v", *debugPort))
 	log.Println("Waiting for the server to be configured...")
 	dm.WaitUntilConfigured()
 	// Runs the server.
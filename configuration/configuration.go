@@ -1,11 +1,13 @@
 package configuration
 
 import (
+	"bytes"
 	"context"
 	"io/ioutil"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 )
 
 // zx define a source
@@ -16,15 +18,41 @@ type pair struct {
 	err  error
 }
 
+// LocalFile returns a source that reads path once at start and again
+// every time the process receives SIGHUP.
 func LocalFile(path string) SourceFunc {
+	return reloading(func() ([]byte, error) { return ioutil.ReadFile(path) }, 0)
+}
+
+// reloading returns a source that calls read at start, on every
+// SIGHUP and, if poll is positive, every poll. Polling only yields
+// data that differs from the previous read.
+func reloading(read func() ([]byte, error), poll time.Duration) SourceFunc {
 	updates := make(chan pair, 1)
-	c := make(chan os.Signal)
+	c := make(chan os.Signal, 1)
 	signal.Notify(c, syscall.SIGHUP)
 	c <- syscall.SIGHUP
+
+	var tick <-chan time.Time
+	if poll > 0 {
+		tick = time.NewTicker(poll).C
+	}
 	go func() {
-		for range c {
-			data, err := ioutil.ReadFile(path)
-			updates <- pair{data: data, err: err}
+		var last pair
+		for {
+			forced := false
+			select {
+			case <-c:
+				forced = true
+			case <-tick:
+			}
+			data, err := read()
+			p := pair{data: data, err: err}
+			if !forced && p.err == nil && last.err == nil && bytes.Equal(p.data, last.data) {
+				continue
+			}
+			last = p
+			updates <- p
 		}
 	}()
 	return func(ctx context.Context) (data []byte, err error) {
@@ -36,7 +64,3 @@ func LocalFile(path string) SourceFunc {
 		}
 	}
 }
-
-func ParseSource(text string) (kind string, path string) {
-	return "file", text
-}
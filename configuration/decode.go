@@ -0,0 +1,124 @@
+package configuration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/notfresh/zxdoorman/proto"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/prototext"
+	goproto "google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v3"
+)
+
+// Configuration formats understood by Decode.
+const (
+	FormatYAML      = "yaml"
+	FormatJSON      = "json"
+	FormatProtoText = "prototext"
+)
+
+func validFormat(format string) bool {
+	switch format {
+	case FormatYAML, FormatJSON, FormatProtoText:
+		return true
+	}
+	return false
+}
+
+// FormatFromPath guesses the format of a configuration from the
+// extension of its path. It defaults to YAML.
+func FormatFromPath(p string) string {
+	if i := strings.IndexAny(p, "?#"); i >= 0 {
+		p = p[:i]
+	}
+	switch strings.ToLower(path.Ext(p)) {
+	case ".json":
+		return FormatJSON
+	case ".textproto", ".textpb", ".pbtxt", ".prototext", ".txtpb":
+		return FormatProtoText
+	}
+	return FormatYAML
+}
+
+// ConfigFormat returns the format of the configuration loaded from
+// src: the explicit one if set, otherwise the one guessed from its
+// path. Directories are always YAML streams.
+func (src *Source) ConfigFormat() string {
+	if src.Format != "" {
+		return src.Format
+	}
+	if src.Scheme == SchemeDir {
+		return FormatYAML
+	}
+	return FormatFromPath(src.Path)
+}
+
+// Decode parses a ResourceRepository in format. Field names follow
+// the protobuf JSON mapping (both identifier_glob and identifierGlob
+// are accepted), enums may be given by name and unknown fields are
+// an error. A YAML stream may hold several documents, whose resources,
+// groups, namespaces and administrators are merged in order.
+func Decode(data []byte, format string) (*proto.ResourceRepository, error) {
+	repo := new(proto.ResourceRepository)
+	switch format {
+	case FormatJSON:
+		if err := protojson.Unmarshal(data, repo); err != nil {
+			return nil, fmt.Errorf("configuration: %v", err)
+		}
+	case FormatProtoText:
+		if err := prototext.Unmarshal(data, repo); err != nil {
+			return nil, fmt.Errorf("configuration: %v", err)
+		}
+	case FormatYAML, "":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		for i := 0; ; i++ {
+			var doc interface{}
+			if err := dec.Decode(&doc); err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, fmt.Errorf("configuration: %v", err)
+			}
+			if doc == nil {
+				continue
+			}
+			// YAML is turned into JSON to get the protobuf JSON
+			// mapping rules.
+			js, err := json.Marshal(doc)
+			if err != nil {
+				return nil, fmt.Errorf("configuration: document %d: %v", i, err)
+			}
+			part := new(proto.ResourceRepository)
+			if err := protojson.Unmarshal(js, part); err != nil {
+				return nil, fmt.Errorf("configuration: document %d: %v", i, err)
+			}
+			goproto.Merge(repo, part)
+		}
+	default:
+		return nil, fmt.Errorf("configuration: unknown format %q", format)
+	}
+	return repo, nil
+}
+
+// Load parses the source description text, reads the configuration
+// from it once and decodes it.
+func Load(ctx context.Context, text string, opts Options) (*proto.ResourceRepository, error) {
+	src, err := ParseSource(text)
+	if err != nil {
+		return nil, err
+	}
+	source, err := Open(src, opts)
+	if err != nil {
+		return nil, err
+	}
+	data, err := source(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return Decode(data, src.ConfigFormat())
+}
@@ -0,0 +1,118 @@
+package configuration
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/notfresh/zxdoorman/proto"
+	goproto "google.golang.org/protobuf/proto"
+)
+
+func TestFormatFromPath(t *testing.T) {
+	for path, want := range map[string]string{
+		"/etc/doorman.yml":                    FormatYAML,
+		"/etc/doorman.yaml":                   FormatYAML,
+		"/etc/doorman":                        FormatYAML,
+		"/etc/doorman.JSON":                   FormatJSON,
+		"http://example.com/doorman.json?a=b": FormatJSON,
+		"/etc/doorman.textproto":              FormatProtoText,
+		"/etc/doorman.pbtxt":                  FormatProtoText,
+	} {
+		if got := FormatFromPath(path); got != want {
+			t.Errorf("FormatFromPath(%q) = %q want %q", path, got, want)
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	want := &proto.ResourceRepository{
+		Resources: []*proto.ResourcePB{
+			{
+				IdentifierGlob: "res1",
+				Capacity:       100,
+				Algo: &proto.AlgorithmPB{
+					Kind:        proto.AlgorithmPB_FAIR,
+					LeaseLength: 15,
+				},
+			},
+		},
+	}
+
+	for _, c := range []struct {
+		format string
+		data   string
+	}{
+		{FormatYAML, "resources:\n- identifier_glob: res1\n  capacity: 100\n  algo:\n    kind: FAIR\n    lease_length: 15\n"},
+		{FormatJSON, `{"resources": [{"identifierGlob": "res1", "capacity": 100, "algo": {"kind": "FAIR", "leaseLength": "15"}}]}`},
+		{FormatProtoText, `resources { identifier_glob: "res1" capacity: 100 algo { kind: FAIR lease_length: 15 } }`},
+	} {
+		got, err := Decode([]byte(c.data), c.format)
+		if err != nil {
+			t.Errorf("Decode(%v): %v", c.format, err)
+			continue
+		}
+		if !goproto.Equal(got, want) {
+			t.Errorf("Decode(%v) = %v want %v", c.format, got, want)
+		}
+	}
+}
+
+func TestDecodeYAMLStream(t *testing.T) {
+	got, err := Decode([]byte("---\nresources:\n- identifier_glob: a\n---\n---\nresources:\n- identifier_glob: b\n"), FormatYAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := 2, len(got.Resources); want != got {
+		t.Fatalf("len(resources) = %v want %v", got, want)
+	}
+	if got.Resources[0].IdentifierGlob != "a" || got.Resources[1].IdentifierGlob != "b" {
+		t.Errorf("resources = %v, want a and b", got.Resources)
+	}
+}
+
+func TestDecodeYAMLStreamNamespaces(t *testing.T) {
+	got, err := Decode([]byte("namespaces:\n- name: search\n  capacity: 100\n---\nnamespaces:\n- name: ads\ngroups:\n- name: batch\n"), FormatYAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Namespaces) != 2 || got.Namespaces[0].GetName() != "search" || got.Namespaces[1].GetName() != "ads" {
+		t.Errorf("namespaces = %v, want search and ads", got.Namespaces)
+	}
+	if len(got.Groups) != 1 {
+		t.Errorf("groups = %v, want batch", got.Groups)
+	}
+}
+
+func TestDecodeStrict(t *testing.T) {
+	for _, c := range []struct {
+		format string
+		data   string
+	}{
+		{FormatYAML, "resources:\n- identifier_glob: res1\n  algorithm:\n    kind: FAIR\n"},
+		{FormatYAML, "resources:\n- identifier_glob: res1\n  algo:\n    kind: FASTEST\n"},
+		{FormatJSON, `{"resources": [{"identifierGlob": "res1", "capacityy": 100}]}`},
+		{FormatProtoText, `resources { identifier_glob: "res1" capacityy: 100 }`},
+		{"xml", `<resources/>`},
+	} {
+		if got, err := Decode([]byte(c.data), c.format); err == nil {
+			t.Errorf("Decode(%v, %q) = %v want error", c.format, c.data, got)
+		}
+	}
+}
+
+func TestDecodeSample(t *testing.T) {
+	data, err := ioutil.ReadFile("../resource-config.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo, err := Decode(data, FormatFromPath("resource-config.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := 2, len(repo.Resources); want != got {
+		t.Fatalf("len(resources) = %v want %v", got, want)
+	}
+	if want, got := int64(15), repo.Resources[0].GetAlgo().GetLeaseLength(); want != got {
+		t.Errorf("lease_length = %v want %v", got, want)
+	}
+}
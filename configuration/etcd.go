@@ -0,0 +1,68 @@
+package configuration
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// etcdKV is a KV backed by an etcd v3 cluster.
+type etcdKV struct {
+	client *clientv3.Client
+}
+
+// NewEtcdKV connects to the etcd v3 cluster at endpoints.
+func NewEtcdKV(endpoints []string, dialTimeout time.Duration) (KV, error) {
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:   endpoints,
+		DialTimeout: dialTimeout,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &etcdKV{client: client}, nil
+}
+
+func (kv *etcdKV) Get(ctx context.Context, key string) ([]byte, int64, error) {
+	resp, err := kv.client.Get(ctx, key)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, resp.Header.Revision, ErrKeyNotFound
+	}
+	return resp.Kvs[0].Value, resp.Header.Revision, nil
+}
+
+func (kv *etcdKV) Watch(ctx context.Context, key string, revision int64) ([]byte, int64, error) {
+	ctx, cancel := context.WithCancel(clientv3.WithRequireLeader(ctx))
+	defer cancel()
+
+	for resp := range kv.client.Watch(ctx, key, clientv3.WithRev(revision+1)) {
+		if resp.CompactRevision != 0 {
+			return nil, resp.CompactRevision, ErrCompacted
+		}
+		if err := resp.Err(); err != nil {
+			return nil, 0, err
+		}
+		if len(resp.Events) == 0 {
+			continue
+		}
+		// Only the latest modification matters.
+		ev := resp.Events[len(resp.Events)-1]
+		if ev.Type == clientv3.EventTypeDelete {
+			return nil, ev.Kv.ModRevision, ErrKeyNotFound
+		}
+		return ev.Kv.Value, ev.Kv.ModRevision, nil
+	}
+	if ctx.Err() != nil {
+		return nil, 0, ctx.Err()
+	}
+	return nil, 0, fmt.Errorf("configuration: watch on %q closed", key)
+}
+
+func (kv *etcdKV) Close() error {
+	return kv.client.Close()
+}
@@ -0,0 +1,85 @@
+package configuration
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+)
+
+// ErrKeyNotFound is returned by a KV when the watched key does not exist.
+var ErrKeyNotFound = errors.New("configuration: key not found")
+
+// ErrCompacted is returned by KV.Watch when the store no longer has
+// the history since the watched revision.
+var ErrCompacted = errors.New("configuration: revision compacted")
+
+// KV is the small part of a key-value store that a configuration
+// source needs: reading a key and waiting for it to change.
+type KV interface {
+	// Get returns the current value of key together with the store
+	// revision at which it was read.
+	Get(ctx context.Context, key string) (value []byte, revision int64, err error)
+	// Watch blocks until key is modified after revision and returns
+	// the new value and the revision of the modification. It returns
+	// ErrCompacted if revision is older than the store's history.
+	Watch(ctx context.Context, key string, revision int64) (value []byte, newRevision int64, err error)
+	Close() error
+}
+
+// kvRetryInterval is how long a KV source waits before retrying a
+// failed read or watch.
+var kvRetryInterval = 5 * time.Second
+
+// KVSource returns a SourceFunc which yields the value of key in kv.
+// The first call returns the current value, every following call
+// blocks until the key is modified. Transient store errors are
+// logged and retried, so every server watching the same key picks
+// up a new configuration as soon as it is written. If the store
+// compacted its history past the last revision seen, the source reads
+// the key again.
+func KVSource(kv KV, key string) SourceFunc {
+	var (
+		revision int64
+		started  bool
+	)
+	return func(ctx context.Context) (data []byte, err error) {
+		for {
+			var rev int64
+			if !started {
+				data, rev, err = kv.Get(ctx, key)
+			} else {
+				data, rev, err = kv.Watch(ctx, key, revision)
+			}
+			if err == nil || err == ErrKeyNotFound {
+				started = true
+				if rev > revision {
+					revision = rev
+				}
+			}
+			if err == nil {
+				return data, nil
+			}
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			if err == ErrCompacted {
+				// Watching again from revision would fail forever.
+				log.Printf("configuration: history of %q compacted past revision %v, reading it again", key, revision)
+				started = false
+				continue
+			}
+			if err == ErrKeyNotFound {
+				// Waits for the key to be created.
+				log.Printf("configuration: %q does not exist, waiting for it", key)
+				continue
+			}
+			log.Printf("configuration: cannot read %q: %v, retrying in %v", key, err, kvRetryInterval)
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(kvRetryInterval):
+			}
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package configuration
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestKVSource(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	kv := NewMemoryKV()
+	kv.Put("/doorman/config", []byte("v1"))
+	source := KVSource(kv, "/doorman/config")
+
+	data, err := source(ctx)
+	if err != nil {
+		t.Fatalf("source: %v", err)
+	}
+	if want, got := "v1", string(data); want != got {
+		t.Errorf("source() = %q want %q", got, want)
+	}
+
+	// Modifications of other keys must not wake the source up.
+	kv.Put("/doorman/other", []byte("other"))
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		kv.Delete("/doorman/config")
+		kv.Put("/doorman/config", []byte("v2"))
+	}()
+
+	data, err = source(ctx)
+	if err != nil {
+		t.Fatalf("source: %v", err)
+	}
+	if want, got := "v2", string(data); want != got {
+		t.Errorf("source() = %q want %q", got, want)
+	}
+}
+
+func TestKVSourceMissingKey(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	kv := NewMemoryKV()
+	source := KVSource(kv, "/doorman/config")
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		kv.Put("/doorman/config", []byte("v1"))
+	}()
+
+	data, err := source(ctx)
+	if err != nil {
+		t.Fatalf("source: %v", err)
+	}
+	if want, got := "v1", string(data); want != got {
+		t.Errorf("source() = %q want %q", got, want)
+	}
+}
+
+func TestKVSourceCompacted(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	kv := NewMemoryKV()
+	kv.Put("/doorman/config", []byte("v1"))
+	source := KVSource(kv, "/doorman/config")
+	if _, err := source(ctx); err != nil {
+		t.Fatalf("source: %v", err)
+	}
+
+	// The store compacts past the revision the source has seen.
+	kv.Put("/doorman/config", []byte("v2"))
+	kv.Compact(kv.Put("/doorman/config", []byte("v3")))
+
+	data, err := source(ctx)
+	if err != nil {
+		t.Fatalf("source: %v", err)
+	}
+	if want, got := "v3", string(data); want != got {
+		t.Errorf("source() = %q want %q", got, want)
+	}
+}
+
+func TestKVSourceCancel(t *testing.T) {
+	kv := NewMemoryKV()
+	kv.Put("/doorman/config", []byte("v1"))
+	source := KVSource(kv, "/doorman/config")
+	if _, err := source(context.Background()); err != nil {
+		t.Fatalf("source: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := source(ctx); err != context.DeadlineExceeded {
+		t.Errorf("source() error = %v want %v", err, context.DeadlineExceeded)
+	}
+}
@@ -0,0 +1,104 @@
+package configuration
+
+import (
+	"context"
+	"sync"
+)
+
+type memoryEntry struct {
+	value    []byte
+	revision int64
+	deleted  bool
+}
+
+// MemoryKV is an in-memory KV. It is meant for tests and for
+// running a single server without an external store.
+type MemoryKV struct {
+	mu       sync.Mutex
+	revision int64
+	// compacted is the revision before which the history is gone.
+	compacted int64
+	entries   map[string]memoryEntry
+	// changed is closed and replaced on every modification.
+	changed chan struct{}
+}
+
+// NewMemoryKV returns an empty MemoryKV.
+func NewMemoryKV() *MemoryKV {
+	return &MemoryKV{
+		entries: make(map[string]memoryEntry),
+		changed: make(chan struct{}),
+	}
+}
+
+// Put sets key to value and returns the revision of the modification.
+func (kv *MemoryKV) Put(key string, value []byte) int64 {
+	return kv.modify(key, memoryEntry{value: append([]byte(nil), value...)})
+}
+
+// Delete removes key and returns the revision of the modification.
+func (kv *MemoryKV) Delete(key string) int64 {
+	return kv.modify(key, memoryEntry{deleted: true})
+}
+
+// Compact forgets the history before revision, like an etcd
+// compaction: watches from an earlier revision fail with
+// ErrCompacted.
+func (kv *MemoryKV) Compact(revision int64) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	if revision > kv.compacted {
+		kv.compacted = revision
+	}
+}
+
+func (kv *MemoryKV) modify(key string, entry memoryEntry) int64 {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	kv.revision++
+	entry.revision = kv.revision
+	kv.entries[key] = entry
+	close(kv.changed)
+	kv.changed = make(chan struct{})
+	return kv.revision
+}
+
+func (kv *MemoryKV) Get(ctx context.Context, key string) ([]byte, int64, error) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	entry, ok := kv.entries[key]
+	if !ok || entry.deleted {
+		return nil, kv.revision, ErrKeyNotFound
+	}
+	return entry.value, kv.revision, nil
+}
+
+func (kv *MemoryKV) Watch(ctx context.Context, key string, revision int64) ([]byte, int64, error) {
+	for {
+		kv.mu.Lock()
+		entry, ok := kv.entries[key]
+		changed := kv.changed
+		compacted := kv.compacted
+		kv.mu.Unlock()
+
+		if revision < compacted {
+			return nil, compacted, ErrCompacted
+		}
+		if ok && entry.revision > revision {
+			if entry.deleted {
+				return nil, entry.revision, ErrKeyNotFound
+			}
+			return entry.value, entry.revision, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, 0, ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+func (kv *MemoryKV) Close() error {
+	return nil
+}
@@ -0,0 +1,205 @@
+package configuration
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Schemes understood by ParseSource.
+const (
+	SchemeFile  = "file"
+	SchemeDir   = "dir"
+	SchemeHTTP  = "http"
+	SchemeHTTPS = "https"
+	SchemeKV    = "kv"
+)
+
+// defaultHTTPPollInterval is used by HTTP sources that do not set a
+// poll interval: unlike files they have no SIGHUP to rely on.
+const defaultHTTPPollInterval = time.Minute
+
+// Source describes where a configuration is loaded from. It is
+// usually obtained from ParseSource.
+type Source struct {
+	Scheme string
+	// Path is the file or directory path, the URL for HTTP sources
+	// and the key for kv sources.
+	Path string
+	// PollInterval, if positive, makes the source check for changes
+	// periodically.
+	PollInterval time.Duration
+	// Format is the format of the configuration, empty if it should
+	// be guessed from the path.
+	Format string
+}
+
+func (src *Source) String() string {
+	if src.Scheme == SchemeHTTP || src.Scheme == SchemeHTTPS {
+		return src.Path
+	}
+	return src.Scheme + ":" + src.Path
+}
+
+// ParseSource parses a source description of the form
+// scheme:path[?poll=interval&format=format], for example
+// file:/etc/doorman.yml, dir:/etc/doorman.d?poll=30s,
+// http://config.example.com/doorman.json or kv:/doorman/config.
+// Text without a scheme is a file path.
+func ParseSource(text string) (*Source, error) {
+	if text == "" {
+		return nil, errors.New("configuration: empty source")
+	}
+	src := &Source{Scheme: SchemeFile, Path: text}
+	if i := strings.Index(text, ":"); i > 0 && !strings.ContainsAny(text[:i], `/\`) {
+		src.Scheme, src.Path = text[:i], text[i+1:]
+	}
+
+	switch src.Scheme {
+	case SchemeFile, SchemeDir, SchemeKV:
+		path, query := src.Path, ""
+		if i := strings.Index(path, "?"); i >= 0 {
+			path, query = path[:i], path[i+1:]
+		}
+		values, err := url.ParseQuery(query)
+		if err != nil {
+			return nil, fmt.Errorf("configuration: invalid options in %q: %v", text, err)
+		}
+		src.Path = path
+		if err := src.setOptions(values); err != nil {
+			return nil, err
+		}
+		if len(values) != 0 {
+			return nil, fmt.Errorf("configuration: unknown options in %q", text)
+		}
+	case SchemeHTTP, SchemeHTTPS:
+		u, err := url.Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("configuration: invalid URL %q: %v", text, err)
+		}
+		// Known options are removed, everything else is part of
+		// the URL.
+		values := u.Query()
+		if err := src.setOptions(values); err != nil {
+			return nil, err
+		}
+		u.RawQuery = values.Encode()
+		src.Path = u.String()
+	default:
+		return nil, fmt.Errorf("configuration: unknown source scheme %q in %q", src.Scheme, text)
+	}
+
+	if src.Path == "" {
+		return nil, fmt.Errorf("configuration: no path in %q", text)
+	}
+	return src, nil
+}
+
+// setOptions reads the source options from values, removing them.
+func (src *Source) setOptions(values url.Values) error {
+	if poll := values.Get("poll"); poll != "" {
+		d, err := time.ParseDuration(poll)
+		if err != nil || d < 0 {
+			return fmt.Errorf("configuration: invalid poll interval %q", poll)
+		}
+		src.PollInterval = d
+	}
+	src.Format = values.Get("format")
+	if src.Format != "" && !validFormat(src.Format) {
+		return fmt.Errorf("configuration: unknown format %q", src.Format)
+	}
+	values.Del("poll")
+	values.Del("format")
+	return nil
+}
+
+// Options holds what Open needs besides the source description.
+type Options struct {
+	// EtcdEndpoints and DialTimeout are used to connect kv sources
+	// to etcd.
+	EtcdEndpoints []string
+	DialTimeout   time.Duration
+	// KV, if not nil, is used by kv sources instead of etcd.
+	KV KV
+}
+
+// Open returns a SourceFunc that loads the configuration described
+// by src.
+func Open(src *Source, opts Options) (SourceFunc, error) {
+	switch src.Scheme {
+	case SchemeFile:
+		return reloading(func() ([]byte, error) { return ioutil.ReadFile(src.Path) }, src.PollInterval), nil
+	case SchemeDir:
+		return reloading(func() ([]byte, error) { return readDir(src.Path) }, src.PollInterval), nil
+	case SchemeHTTP, SchemeHTTPS:
+		poll := src.PollInterval
+		if poll == 0 {
+			poll = defaultHTTPPollInterval
+		}
+		return reloading(func() ([]byte, error) { return fetch(src.Path) }, poll), nil
+	case SchemeKV:
+		kv := opts.KV
+		if kv == nil {
+			if len(opts.EtcdEndpoints) == 0 {
+				return nil, fmt.Errorf("configuration: %v needs etcd endpoints", src)
+			}
+			var err error
+			if kv, err = NewEtcdKV(opts.EtcdEndpoints, opts.DialTimeout); err != nil {
+				return nil, err
+			}
+		}
+		return KVSource(kv, src.Path), nil
+	}
+	return nil, fmt.Errorf("configuration: unknown source scheme %q", src.Scheme)
+}
+
+// readDir reads every regular, non hidden file in dir in lexical
+// order and joins them as a YAML stream, one document per file.
+func readDir(dir string) ([]byte, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if e.Mode().IsRegular() && !strings.HasPrefix(e.Name(), ".") {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	for _, name := range names {
+		data, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteString("---\n")
+		buf.Write(data)
+		if len(data) > 0 && data[len(data)-1] != '\n' {
+			buf.WriteByte('\n')
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+func fetch(url string) ([]byte, error) {
+	resp, err := httpClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("configuration: GET %v: %v", url, resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
@@ -0,0 +1,121 @@
+package configuration
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseSource(t *testing.T) {
+	for _, c := range []struct {
+		text string
+		want Source
+	}{
+		{"/etc/doorman.yml", Source{Scheme: SchemeFile, Path: "/etc/doorman.yml"}},
+		{"./doorman.yml", Source{Scheme: SchemeFile, Path: "./doorman.yml"}},
+		{"file:/etc/doorman.yml", Source{Scheme: SchemeFile, Path: "/etc/doorman.yml"}},
+		{"file:/etc/doorman.cfg?format=prototext&poll=30s", Source{Scheme: SchemeFile, Path: "/etc/doorman.cfg", Format: "prototext", PollInterval: 30 * time.Second}},
+		{"dir:/etc/doorman.d", Source{Scheme: SchemeDir, Path: "/etc/doorman.d"}},
+		{"kv:/doorman/config", Source{Scheme: SchemeKV, Path: "/doorman/config"}},
+		{"http://example.com/doorman.json", Source{Scheme: SchemeHTTP, Path: "http://example.com/doorman.json"}},
+		{"https://example.com/c?env=prod&poll=1m", Source{Scheme: SchemeHTTPS, Path: "https://example.com/c?env=prod", PollInterval: time.Minute}},
+	} {
+		got, err := ParseSource(c.text)
+		if err != nil {
+			t.Errorf("ParseSource(%q): %v", c.text, err)
+			continue
+		}
+		if !reflect.DeepEqual(*got, c.want) {
+			t.Errorf("ParseSource(%q) = %+v want %+v", c.text, *got, c.want)
+		}
+	}
+}
+
+func TestParseSourceErrors(t *testing.T) {
+	for _, text := range []string{
+		"",
+		"ftp://example.com/doorman.yml",
+		"etcd:/doorman/config",
+		"file:",
+		"file:/etc/doorman.yml?poll=often",
+		"dir:/etc/doorman.d?color=blue",
+	} {
+		if src, err := ParseSource(text); err == nil {
+			t.Errorf("ParseSource(%q) = %+v want error", text, src)
+		}
+	}
+}
+
+func TestOpenDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "doorman")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for name, content := range map[string]string{
+		"b.yml":   "b: 2",
+		"a.yml":   "a: 1\n",
+		".hidden": "hidden: 3\n",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	source, err := Open(&Source{Scheme: SchemeDir, Path: dir}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := source(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := "---\na: 1\n---\nb: 2\n", string(data); want != got {
+		t.Errorf("source() = %q want %q", got, want)
+	}
+}
+
+func TestOpenHTTP(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("resources: []"))
+	}))
+	defer ts.Close()
+
+	src, err := ParseSource(ts.URL + "/config?poll=1h")
+	if err != nil {
+		t.Fatal(err)
+	}
+	source, err := Open(src, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := source(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := "resources: []", string(data); want != got {
+		t.Errorf("source() = %q want %q", got, want)
+	}
+}
+
+func TestOpenKV(t *testing.T) {
+	if _, err := Open(&Source{Scheme: SchemeKV, Path: "/doorman/config"}, Options{}); err == nil {
+		t.Errorf("Open without etcd endpoints succeeded")
+	}
+
+	kv := NewMemoryKV()
+	kv.Put("/doorman/config", []byte("v1"))
+	source, err := Open(&Source{Scheme: SchemeKV, Path: "/doorman/config"}, Options{KV: kv})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, err := source(context.Background()); err != nil || string(data) != "v1" {
+		t.Errorf("source() = %q, %v want %q", data, err, "v1")
+	}
+}
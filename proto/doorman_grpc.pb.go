@@ -23,6 +23,10 @@ const _ = grpc.SupportPackageIsVersion7
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type CapacityClient interface {
 	GetCapacity(ctx context.Context, in *GetCapacityRequest, opts ...grpc.CallOption) (*GetCapacityResponse, error)
+	// WatchCapacity sends the leases for the requested resources, and
+	// new ones whenever they change or need renewing, for as long as
+	// the stream is open. Leases expire normally once it is closed.
+	WatchCapacity(ctx context.Context, in *GetCapacityRequest, opts ...grpc.CallOption) (Capacity_WatchCapacityClient, error)
 }
 
 type capacityClient struct {
@@ -42,11 +46,47 @@ func (c *capacityClient) GetCapacity(ctx context.Context, in *GetCapacityRequest
 	return out, nil
 }
 
+func (c *capacityClient) WatchCapacity(ctx context.Context, in *GetCapacityRequest, opts ...grpc.CallOption) (Capacity_WatchCapacityClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Capacity_ServiceDesc.Streams[0], "/doorman.Capacity/WatchCapacity", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &capacityWatchCapacityClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Capacity_WatchCapacityClient interface {
+	Recv() (*GetCapacityResponse, error)
+	grpc.ClientStream
+}
+
+type capacityWatchCapacityClient struct {
+	grpc.ClientStream
+}
+
+func (x *capacityWatchCapacityClient) Recv() (*GetCapacityResponse, error) {
+	m := new(GetCapacityResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // CapacityServer is the server API for Capacity service.
 // All implementations must embed UnimplementedCapacityServer
 // for forward compatibility
 type CapacityServer interface {
 	GetCapacity(context.Context, *GetCapacityRequest) (*GetCapacityResponse, error)
+	// WatchCapacity sends the leases for the requested resources, and
+	// new ones whenever they change or need renewing, for as long as
+	// the stream is open. Leases expire normally once it is closed.
+	WatchCapacity(*GetCapacityRequest, Capacity_WatchCapacityServer) error
 	mustEmbedUnimplementedCapacityServer()
 }
 
@@ -57,6 +97,9 @@ type UnimplementedCapacityServer struct {
 func (UnimplementedCapacityServer) GetCapacity(context.Context, *GetCapacityRequest) (*GetCapacityResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetCapacity not implemented")
 }
+func (UnimplementedCapacityServer) WatchCapacity(*GetCapacityRequest, Capacity_WatchCapacityServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchCapacity not implemented")
+}
 func (UnimplementedCapacityServer) mustEmbedUnimplementedCapacityServer() {}
 
 // UnsafeCapacityServer may be embedded to opt out of forward compatibility for this service.
@@ -88,6 +131,27 @@ func _Capacity_GetCapacity_Handler(srv interface{}, ctx context.Context, dec fun
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Capacity_WatchCapacity_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GetCapacityRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CapacityServer).WatchCapacity(m, &capacityWatchCapacityServer{stream})
+}
+
+type Capacity_WatchCapacityServer interface {
+	Send(*GetCapacityResponse) error
+	grpc.ServerStream
+}
+
+type capacityWatchCapacityServer struct {
+	grpc.ServerStream
+}
+
+func (x *capacityWatchCapacityServer) Send(m *GetCapacityResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 // Capacity_ServiceDesc is the grpc.ServiceDesc for Capacity service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -100,6 +164,12 @@ var Capacity_ServiceDesc = grpc.ServiceDesc{
 			Handler:    _Capacity_GetCapacity_Handler,
 		},
 	},
-	Streams:  []grpc.StreamDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchCapacity",
+			Handler:       _Capacity_WatchCapacity_Handler,
+			ServerStreams: true,
+		},
+	},
 	Metadata: "doorman.proto",
 }
@@ -25,7 +25,13 @@ type AlgorithmPB_Kind int32
 const (
 	AlgorithmPB_NO_ALGORITHM AlgorithmPB_Kind = 0
 	AlgorithmPB_STATIC       AlgorithmPB_Kind = 1
-	AlgorithmPB_FAIR         AlgorithmPB_Kind = 2
+	// Splits the capacity between the clients in proportion to their
+	// weights.
+	AlgorithmPB_FAIR AlgorithmPB_Kind = 2
+	// Splits the capacity between the clients in proportion to their
+	// weights, whatever they want, and gives them as burst what they
+	// did not use of it, up to the burst_size parameter.
+	AlgorithmPB_TOKEN_BUCKET AlgorithmPB_Kind = 3
 )
 
 // Enum value maps for AlgorithmPB_Kind.
@@ -34,11 +40,13 @@ var (
 		0: "NO_ALGORITHM",
 		1: "STATIC",
 		2: "FAIR",
+		3: "TOKEN_BUCKET",
 	}
 	AlgorithmPB_Kind_value = map[string]int32{
 		"NO_ALGORITHM": 0,
 		"STATIC":       1,
 		"FAIR":         2,
+		"TOKEN_BUCKET": 3,
 	}
 )
 
@@ -159,6 +167,39 @@ type ResourcePB struct {
 	// zx resource has a algorithm, but algorithm can be use
 	Algo        *AlgorithmPB `protobuf:"bytes,4,opt,name=algo,proto3" json:"algo,omitempty"`
 	Description string       `protobuf:"bytes,5,opt,name=description,proto3" json:"description,omitempty"`
+	// Unix time (seconds) after which the resource has no capacity, 0 for never.
+	ExpiryTime int64 `protobuf:"varint,6,opt,name=expiry_time,json=expiryTime,proto3" json:"expiry_time,omitempty"`
+	// Seconds after the configuration is loaded after which the resource
+	// has no capacity, 0 for never. The earlier of expiry_time and ttl wins.
+	Ttl int64 `protobuf:"varint,7,opt,name=ttl,proto3" json:"ttl,omitempty"`
+	// Globs of the client ids allowed to request the resource. If
+	// neither allowed_clients nor allowed_groups is set every client is
+	// allowed.
+	AllowedClients []string `protobuf:"bytes,8,rep,name=allowed_clients,json=allowedClients,proto3" json:"allowed_clients,omitempty"`
+	// Names of the client groups allowed to request the resource.
+	AllowedGroups []string `protobuf:"bytes,9,rep,name=allowed_groups,json=allowedGroups,proto3" json:"allowed_groups,omitempty"`
+	// Id of a resource whose capacity this one shares with the other
+	// resources naming it as parent: the capacity the children give out
+	// never exceeds the parent's capacity in total.
+	Parent string `protobuf:"bytes,10,opt,name=parent,proto3" json:"parent,omitempty"`
+	// Bounds of the capacity of the clients, the first rule matching a
+	// client applying to it.
+	ClientRules []*ClientRulePB `protobuf:"bytes,11,rep,name=client_rules,json=clientRules,proto3" json:"client_rules,omitempty"`
+	// Capacities overriding capacity at some times, the first window
+	// active at a time applying.
+	Schedule []*ScheduleWindowPB `protobuf:"bytes,12,rep,name=schedule,proto3" json:"schedule,omitempty"`
+	// Name of a group of resources lending each other the capacity they
+	// do not want. What a resource lends is reclaimed as the borrowers
+	// refresh their leases once its demand returns.
+	BorrowingGroup string `protobuf:"bytes,13,opt,name=borrowing_group,json=borrowingGroup,proto3" json:"borrowing_group,omitempty"`
+	// Capacities of the resources matching a template, an identifier
+	// glob with named captures like "db/{shard}/writes", the first
+	// override matching the captured values applying.
+	Overrides []*CaptureOverridePB `protobuf:"bytes,14,rep,name=overrides,proto3" json:"overrides,omitempty"`
+	// Arithmetic expression of the numeric captured values giving the
+	// capacity of the resources matching a template that no override
+	// matches, like "{replicas} * 100".
+	CapacityExpression string `protobuf:"bytes,15,opt,name=capacity_expression,json=capacityExpression,proto3" json:"capacity_expression,omitempty"`
 }
 
 func (x *ResourcePB) Reset() {
@@ -228,18 +269,476 @@ func (x *ResourcePB) GetDescription() string {
 	return ""
 }
 
+func (x *ResourcePB) GetExpiryTime() int64 {
+	if x != nil {
+		return x.ExpiryTime
+	}
+	return 0
+}
+
+func (x *ResourcePB) GetTtl() int64 {
+	if x != nil {
+		return x.Ttl
+	}
+	return 0
+}
+
+func (x *ResourcePB) GetAllowedClients() []string {
+	if x != nil {
+		return x.AllowedClients
+	}
+	return nil
+}
+
+func (x *ResourcePB) GetAllowedGroups() []string {
+	if x != nil {
+		return x.AllowedGroups
+	}
+	return nil
+}
+
+func (x *ResourcePB) GetParent() string {
+	if x != nil {
+		return x.Parent
+	}
+	return ""
+}
+
+func (x *ResourcePB) GetClientRules() []*ClientRulePB {
+	if x != nil {
+		return x.ClientRules
+	}
+	return nil
+}
+
+func (x *ResourcePB) GetSchedule() []*ScheduleWindowPB {
+	if x != nil {
+		return x.Schedule
+	}
+	return nil
+}
+
+func (x *ResourcePB) GetBorrowingGroup() string {
+	if x != nil {
+		return x.BorrowingGroup
+	}
+	return ""
+}
+
+func (x *ResourcePB) GetOverrides() []*CaptureOverridePB {
+	if x != nil {
+		return x.Overrides
+	}
+	return nil
+}
+
+func (x *ResourcePB) GetCapacityExpression() string {
+	if x != nil {
+		return x.CapacityExpression
+	}
+	return ""
+}
+
+// CaptureOverridePB overrides the capacity of the resources matching
+// a template whose captured value matches value.
+type CaptureOverridePB struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Name of the capture, without the braces.
+	Capture string `protobuf:"bytes,1,opt,name=capture,proto3" json:"capture,omitempty"`
+	// Glob of the captured value.
+	Value        string `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	Capacity     int32  `protobuf:"varint,3,opt,name=capacity,proto3" json:"capacity,omitempty"`
+	SafeCapacity int32  `protobuf:"varint,4,opt,name=safe_capacity,json=safeCapacity,proto3" json:"safe_capacity,omitempty"`
+}
+
+func (x *CaptureOverridePB) Reset() {
+	*x = CaptureOverridePB{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_resource_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CaptureOverridePB) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CaptureOverridePB) ProtoMessage() {}
+
+func (x *CaptureOverridePB) ProtoReflect() protoreflect.Message {
+	mi := &file_resource_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CaptureOverridePB.ProtoReflect.Descriptor instead.
+func (*CaptureOverridePB) Descriptor() ([]byte, []int) {
+	return file_resource_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *CaptureOverridePB) GetCapture() string {
+	if x != nil {
+		return x.Capture
+	}
+	return ""
+}
+
+func (x *CaptureOverridePB) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *CaptureOverridePB) GetCapacity() int32 {
+	if x != nil {
+		return x.Capacity
+	}
+	return 0
+}
+
+func (x *CaptureOverridePB) GetSafeCapacity() int32 {
+	if x != nil {
+		return x.SafeCapacity
+	}
+	return 0
+}
+
+// ScheduleWindowPB is a recurring time window during which a resource
+// has another capacity.
+type ScheduleWindowPB struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Days of the week the window starts on: comma separated names or
+	// ranges of names, like "mon-fri" or "sat,sun". Empty or "*" for
+	// every day.
+	Days string `protobuf:"bytes,1,opt,name=days,proto3" json:"days,omitempty"`
+	// Time of the day the window starts and ends at, as "15:04". A
+	// window ending before it starts ends the next day, and one ending
+	// when it starts lasts the whole day.
+	Start string `protobuf:"bytes,2,opt,name=start,proto3" json:"start,omitempty"`
+	End   string `protobuf:"bytes,3,opt,name=end,proto3" json:"end,omitempty"`
+	// IANA name of the time zone of the window, like "Europe/Paris",
+	// empty for UTC.
+	TimeZone string `protobuf:"bytes,4,opt,name=time_zone,json=timeZone,proto3" json:"time_zone,omitempty"`
+	// Capacity of the resource during the window.
+	Capacity int32 `protobuf:"varint,5,opt,name=capacity,proto3" json:"capacity,omitempty"`
+}
+
+func (x *ScheduleWindowPB) Reset() {
+	*x = ScheduleWindowPB{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_resource_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ScheduleWindowPB) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ScheduleWindowPB) ProtoMessage() {}
+
+func (x *ScheduleWindowPB) ProtoReflect() protoreflect.Message {
+	mi := &file_resource_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ScheduleWindowPB.ProtoReflect.Descriptor instead.
+func (*ScheduleWindowPB) Descriptor() ([]byte, []int) {
+	return file_resource_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ScheduleWindowPB) GetDays() string {
+	if x != nil {
+		return x.Days
+	}
+	return ""
+}
+
+func (x *ScheduleWindowPB) GetStart() string {
+	if x != nil {
+		return x.Start
+	}
+	return ""
+}
+
+func (x *ScheduleWindowPB) GetEnd() string {
+	if x != nil {
+		return x.End
+	}
+	return ""
+}
+
+func (x *ScheduleWindowPB) GetTimeZone() string {
+	if x != nil {
+		return x.TimeZone
+	}
+	return ""
+}
+
+func (x *ScheduleWindowPB) GetCapacity() int32 {
+	if x != nil {
+		return x.Capacity
+	}
+	return 0
+}
+
+// ClientRulePB bounds the capacity given to the clients of a resource.
+type ClientRulePB struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Glob of the client ids the rule applies to.
+	ClientGlob string `protobuf:"bytes,1,opt,name=client_glob,json=clientGlob,proto3" json:"client_glob,omitempty"`
+	// Capacity each matching client gets, up to what it wants, whatever
+	// the algorithm decides. It is reserved out of the capacity of the
+	// resource while the client holds a lease.
+	MinCapacity int32 `protobuf:"varint,2,opt,name=min_capacity,json=minCapacity,proto3" json:"min_capacity,omitempty"`
+	// Capacity no matching client gets more than, 0 for no maximum.
+	MaxCapacity int32 `protobuf:"varint,3,opt,name=max_capacity,json=maxCapacity,proto3" json:"max_capacity,omitempty"`
+	// Weight of each matching client in fair share algorithms, 0 for
+	// the weight the client asks for.
+	Weight float64 `protobuf:"fixed64,4,opt,name=weight,proto3" json:"weight,omitempty"`
+}
+
+func (x *ClientRulePB) Reset() {
+	*x = ClientRulePB{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_resource_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ClientRulePB) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClientRulePB) ProtoMessage() {}
+
+func (x *ClientRulePB) ProtoReflect() protoreflect.Message {
+	mi := &file_resource_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ClientRulePB.ProtoReflect.Descriptor instead.
+func (*ClientRulePB) Descriptor() ([]byte, []int) {
+	return file_resource_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ClientRulePB) GetClientGlob() string {
+	if x != nil {
+		return x.ClientGlob
+	}
+	return ""
+}
+
+func (x *ClientRulePB) GetMinCapacity() int32 {
+	if x != nil {
+		return x.MinCapacity
+	}
+	return 0
+}
+
+func (x *ClientRulePB) GetMaxCapacity() int32 {
+	if x != nil {
+		return x.MaxCapacity
+	}
+	return 0
+}
+
+func (x *ClientRulePB) GetWeight() float64 {
+	if x != nil {
+		return x.Weight
+	}
+	return 0
+}
+
+// ClientGroupPB names a group of clients, for resource access control.
+type ClientGroupPB struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// Globs of the client ids in the group.
+	Clients []string `protobuf:"bytes,2,rep,name=clients,proto3" json:"clients,omitempty"`
+}
+
+func (x *ClientGroupPB) Reset() {
+	*x = ClientGroupPB{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_resource_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ClientGroupPB) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClientGroupPB) ProtoMessage() {}
+
+func (x *ClientGroupPB) ProtoReflect() protoreflect.Message {
+	mi := &file_resource_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ClientGroupPB.ProtoReflect.Descriptor instead.
+func (*ClientGroupPB) Descriptor() ([]byte, []int) {
+	return file_resource_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ClientGroupPB) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ClientGroupPB) GetClients() []string {
+	if x != nil {
+		return x.Clients
+	}
+	return nil
+}
+
+// NamespacePB is the resources of a team. Their ids are qualified by
+// the name of the namespace, like "search:index/writes", and the
+// parents and borrowing groups they name are those of the namespace.
+type NamespacePB struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Name of the namespace, without colons.
+	Name      string        `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Resources []*ResourcePB `protobuf:"bytes,2,rep,name=resources,proto3" json:"resources,omitempty"`
+	// Capacity the resources of the namespace give out in total, 0 for
+	// no limit.
+	Capacity int32 `protobuf:"varint,3,opt,name=capacity,proto3" json:"capacity,omitempty"`
+	// Globs of the identities that may use the admin service for the
+	// namespace. They may read its configuration history but not roll
+	// the configuration back, which changes every namespace: only the
+	// administrators of the repository may.
+	Administrators []string `protobuf:"bytes,4,rep,name=administrators,proto3" json:"administrators,omitempty"`
+}
+
+func (x *NamespacePB) Reset() {
+	*x = NamespacePB{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_resource_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *NamespacePB) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NamespacePB) ProtoMessage() {}
+
+func (x *NamespacePB) ProtoReflect() protoreflect.Message {
+	mi := &file_resource_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NamespacePB.ProtoReflect.Descriptor instead.
+func (*NamespacePB) Descriptor() ([]byte, []int) {
+	return file_resource_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *NamespacePB) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *NamespacePB) GetResources() []*ResourcePB {
+	if x != nil {
+		return x.Resources
+	}
+	return nil
+}
+
+func (x *NamespacePB) GetCapacity() int32 {
+	if x != nil {
+		return x.Capacity
+	}
+	return 0
+}
+
+func (x *NamespacePB) GetAdministrators() []string {
+	if x != nil {
+		return x.Administrators
+	}
+	return nil
+}
+
 type ResourceRepository struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Resources []*ResourcePB `protobuf:"bytes,1,rep,name=resources,proto3" json:"resources,omitempty"`
+	Resources  []*ResourcePB    `protobuf:"bytes,1,rep,name=resources,proto3" json:"resources,omitempty"`
+	Groups     []*ClientGroupPB `protobuf:"bytes,2,rep,name=groups,proto3" json:"groups,omitempty"`
+	Namespaces []*NamespacePB   `protobuf:"bytes,3,rep,name=namespaces,proto3" json:"namespaces,omitempty"`
+	// Globs of the identities that may use the admin service, for the
+	// whole repository and for every namespace. Without any here nor in
+	// the namespaces, the admin service is open to all.
+	Administrators []string `protobuf:"bytes,4,rep,name=administrators,proto3" json:"administrators,omitempty"`
 }
 
 func (x *ResourceRepository) Reset() {
 	*x = ResourceRepository{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_resource_proto_msgTypes[2]
+		mi := &file_resource_proto_msgTypes[7]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -252,7 +751,7 @@ func (x *ResourceRepository) String() string {
 func (*ResourceRepository) ProtoMessage() {}
 
 func (x *ResourceRepository) ProtoReflect() protoreflect.Message {
-	mi := &file_resource_proto_msgTypes[2]
+	mi := &file_resource_proto_msgTypes[7]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -265,7 +764,7 @@ func (x *ResourceRepository) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ResourceRepository.ProtoReflect.Descriptor instead.
 func (*ResourceRepository) Descriptor() ([]byte, []int) {
-	return file_resource_proto_rawDescGZIP(), []int{2}
+	return file_resource_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *ResourceRepository) GetResources() []*ResourcePB {
@@ -275,6 +774,27 @@ func (x *ResourceRepository) GetResources() []*ResourcePB {
 	return nil
 }
 
+func (x *ResourceRepository) GetGroups() []*ClientGroupPB {
+	if x != nil {
+		return x.Groups
+	}
+	return nil
+}
+
+func (x *ResourceRepository) GetNamespaces() []*NamespacePB {
+	if x != nil {
+		return x.Namespaces
+	}
+	return nil
+}
+
+func (x *ResourceRepository) GetAdministrators() []string {
+	if x != nil {
+		return x.Administrators
+	}
+	return nil
+}
+
 type AlgorithmPB_NamedParamter struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -287,7 +807,7 @@ type AlgorithmPB_NamedParamter struct {
 func (x *AlgorithmPB_NamedParamter) Reset() {
 	*x = AlgorithmPB_NamedParamter{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_resource_proto_msgTypes[3]
+		mi := &file_resource_proto_msgTypes[8]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -300,7 +820,7 @@ func (x *AlgorithmPB_NamedParamter) String() string {
 func (*AlgorithmPB_NamedParamter) ProtoMessage() {}
 
 func (x *AlgorithmPB_NamedParamter) ProtoReflect() protoreflect.Message {
-	mi := &file_resource_proto_msgTypes[3]
+	mi := &file_resource_proto_msgTypes[8]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -334,7 +854,7 @@ var File_resource_proto protoreflect.FileDescriptor
 
 var file_resource_proto_rawDesc = []byte{
 	0x0a, 0x0e, 0x72, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
-	0x12, 0x07, 0x64, 0x6f, 0x6f, 0x72, 0x6d, 0x61, 0x6e, 0x22, 0xeb, 0x02, 0x0a, 0x0b, 0x41, 0x6c,
+	0x12, 0x07, 0x64, 0x6f, 0x6f, 0x72, 0x6d, 0x61, 0x6e, 0x22, 0xfd, 0x02, 0x0a, 0x0b, 0x41, 0x6c,
 	0x67, 0x6f, 0x72, 0x69, 0x74, 0x68, 0x6d, 0x50, 0x42, 0x12, 0x2d, 0x0a, 0x04, 0x6b, 0x69, 0x6e,
 	0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x19, 0x2e, 0x64, 0x6f, 0x6f, 0x72, 0x6d, 0x61,
 	0x6e, 0x2e, 0x41, 0x6c, 0x67, 0x6f, 0x72, 0x69, 0x74, 0x68, 0x6d, 0x50, 0x42, 0x2e, 0x4b, 0x69,
@@ -354,30 +874,105 @@ var file_resource_proto_rawDesc = []byte{
 	0x4e, 0x61, 0x6d, 0x65, 0x64, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x74, 0x65, 0x72, 0x12, 0x12, 0x0a,
 	0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d,
 	0x65, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09,
-	0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x22, 0x2e, 0x0a, 0x04, 0x4b, 0x69, 0x6e, 0x64, 0x12,
+	0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x22, 0x40, 0x0a, 0x04, 0x4b, 0x69, 0x6e, 0x64, 0x12,
 	0x10, 0x0a, 0x0c, 0x4e, 0x4f, 0x5f, 0x41, 0x4c, 0x47, 0x4f, 0x52, 0x49, 0x54, 0x48, 0x4d, 0x10,
 	0x00, 0x12, 0x0a, 0x0a, 0x06, 0x53, 0x54, 0x41, 0x54, 0x49, 0x43, 0x10, 0x01, 0x12, 0x08, 0x0a,
-	0x04, 0x46, 0x41, 0x49, 0x52, 0x10, 0x02, 0x22, 0xc2, 0x01, 0x0a, 0x0a, 0x52, 0x65, 0x73, 0x6f,
-	0x75, 0x72, 0x63, 0x65, 0x50, 0x42, 0x12, 0x27, 0x0a, 0x0f, 0x69, 0x64, 0x65, 0x6e, 0x74, 0x69,
-	0x66, 0x69, 0x65, 0x72, 0x5f, 0x67, 0x6c, 0x6f, 0x62, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
-	0x0e, 0x69, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x66, 0x69, 0x65, 0x72, 0x47, 0x6c, 0x6f, 0x62, 0x12,
-	0x1a, 0x0a, 0x08, 0x63, 0x61, 0x70, 0x61, 0x63, 0x69, 0x74, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28,
-	0x05, 0x52, 0x08, 0x63, 0x61, 0x70, 0x61, 0x63, 0x69, 0x74, 0x79, 0x12, 0x23, 0x0a, 0x0d, 0x73,
-	0x61, 0x66, 0x65, 0x5f, 0x63, 0x61, 0x70, 0x61, 0x63, 0x69, 0x74, 0x79, 0x18, 0x03, 0x20, 0x01,
-	0x28, 0x05, 0x52, 0x0c, 0x73, 0x61, 0x66, 0x65, 0x43, 0x61, 0x70, 0x61, 0x63, 0x69, 0x74, 0x79,
-	0x12, 0x28, 0x0a, 0x04, 0x61, 0x6c, 0x67, 0x6f, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x14,
-	0x2e, 0x64, 0x6f, 0x6f, 0x72, 0x6d, 0x61, 0x6e, 0x2e, 0x41, 0x6c, 0x67, 0x6f, 0x72, 0x69, 0x74,
-	0x68, 0x6d, 0x50, 0x42, 0x52, 0x04, 0x61, 0x6c, 0x67, 0x6f, 0x12, 0x20, 0x0a, 0x0b, 0x64, 0x65,
-	0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52,
-	0x0b, 0x64, 0x65, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x22, 0x47, 0x0a, 0x12,
-	0x52, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x52, 0x65, 0x70, 0x6f, 0x73, 0x69, 0x74, 0x6f,
-	0x72, 0x79, 0x12, 0x31, 0x0a, 0x09, 0x72, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x73, 0x18,
-	0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x64, 0x6f, 0x6f, 0x72, 0x6d, 0x61, 0x6e, 0x2e,
+	0x04, 0x46, 0x41, 0x49, 0x52, 0x10, 0x02, 0x12, 0x10, 0x0a, 0x0c, 0x54, 0x4f, 0x4b, 0x45, 0x4e,
+	0x5f, 0x42, 0x55, 0x43, 0x4b, 0x45, 0x54, 0x10, 0x03, 0x22, 0xe2, 0x04, 0x0a, 0x0a, 0x52, 0x65,
+	0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x50, 0x42, 0x12, 0x27, 0x0a, 0x0f, 0x69, 0x64, 0x65, 0x6e,
+	0x74, 0x69, 0x66, 0x69, 0x65, 0x72, 0x5f, 0x67, 0x6c, 0x6f, 0x62, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x0e, 0x69, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x66, 0x69, 0x65, 0x72, 0x47, 0x6c, 0x6f,
+	0x62, 0x12, 0x1a, 0x0a, 0x08, 0x63, 0x61, 0x70, 0x61, 0x63, 0x69, 0x74, 0x79, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x05, 0x52, 0x08, 0x63, 0x61, 0x70, 0x61, 0x63, 0x69, 0x74, 0x79, 0x12, 0x23, 0x0a,
+	0x0d, 0x73, 0x61, 0x66, 0x65, 0x5f, 0x63, 0x61, 0x70, 0x61, 0x63, 0x69, 0x74, 0x79, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x05, 0x52, 0x0c, 0x73, 0x61, 0x66, 0x65, 0x43, 0x61, 0x70, 0x61, 0x63, 0x69,
+	0x74, 0x79, 0x12, 0x28, 0x0a, 0x04, 0x61, 0x6c, 0x67, 0x6f, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b,
+	0x32, 0x14, 0x2e, 0x64, 0x6f, 0x6f, 0x72, 0x6d, 0x61, 0x6e, 0x2e, 0x41, 0x6c, 0x67, 0x6f, 0x72,
+	0x69, 0x74, 0x68, 0x6d, 0x50, 0x42, 0x52, 0x04, 0x61, 0x6c, 0x67, 0x6f, 0x12, 0x20, 0x0a, 0x0b,
+	0x64, 0x65, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x05, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x0b, 0x64, 0x65, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x1f,
+	0x0a, 0x0b, 0x65, 0x78, 0x70, 0x69, 0x72, 0x79, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x06, 0x20,
+	0x01, 0x28, 0x03, 0x52, 0x0a, 0x65, 0x78, 0x70, 0x69, 0x72, 0x79, 0x54, 0x69, 0x6d, 0x65, 0x12,
+	0x10, 0x0a, 0x03, 0x74, 0x74, 0x6c, 0x18, 0x07, 0x20, 0x01, 0x28, 0x03, 0x52, 0x03, 0x74, 0x74,
+	0x6c, 0x12, 0x27, 0x0a, 0x0f, 0x61, 0x6c, 0x6c, 0x6f, 0x77, 0x65, 0x64, 0x5f, 0x63, 0x6c, 0x69,
+	0x65, 0x6e, 0x74, 0x73, 0x18, 0x08, 0x20, 0x03, 0x28, 0x09, 0x52, 0x0e, 0x61, 0x6c, 0x6c, 0x6f,
+	0x77, 0x65, 0x64, 0x43, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x73, 0x12, 0x25, 0x0a, 0x0e, 0x61, 0x6c,
+	0x6c, 0x6f, 0x77, 0x65, 0x64, 0x5f, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x73, 0x18, 0x09, 0x20, 0x03,
+	0x28, 0x09, 0x52, 0x0d, 0x61, 0x6c, 0x6c, 0x6f, 0x77, 0x65, 0x64, 0x47, 0x72, 0x6f, 0x75, 0x70,
+	0x73, 0x12, 0x16, 0x0a, 0x06, 0x70, 0x61, 0x72, 0x65, 0x6e, 0x74, 0x18, 0x0a, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x06, 0x70, 0x61, 0x72, 0x65, 0x6e, 0x74, 0x12, 0x38, 0x0a, 0x0c, 0x63, 0x6c, 0x69,
+	0x65, 0x6e, 0x74, 0x5f, 0x72, 0x75, 0x6c, 0x65, 0x73, 0x18, 0x0b, 0x20, 0x03, 0x28, 0x0b, 0x32,
+	0x15, 0x2e, 0x64, 0x6f, 0x6f, 0x72, 0x6d, 0x61, 0x6e, 0x2e, 0x43, 0x6c, 0x69, 0x65, 0x6e, 0x74,
+	0x52, 0x75, 0x6c, 0x65, 0x50, 0x42, 0x52, 0x0b, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x52, 0x75,
+	0x6c, 0x65, 0x73, 0x12, 0x35, 0x0a, 0x08, 0x73, 0x63, 0x68, 0x65, 0x64, 0x75, 0x6c, 0x65, 0x18,
+	0x0c, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x19, 0x2e, 0x64, 0x6f, 0x6f, 0x72, 0x6d, 0x61, 0x6e, 0x2e,
+	0x53, 0x63, 0x68, 0x65, 0x64, 0x75, 0x6c, 0x65, 0x57, 0x69, 0x6e, 0x64, 0x6f, 0x77, 0x50, 0x42,
+	0x52, 0x08, 0x73, 0x63, 0x68, 0x65, 0x64, 0x75, 0x6c, 0x65, 0x12, 0x27, 0x0a, 0x0f, 0x62, 0x6f,
+	0x72, 0x72, 0x6f, 0x77, 0x69, 0x6e, 0x67, 0x5f, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x18, 0x0d, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x0e, 0x62, 0x6f, 0x72, 0x72, 0x6f, 0x77, 0x69, 0x6e, 0x67, 0x47, 0x72,
+	0x6f, 0x75, 0x70, 0x12, 0x38, 0x0a, 0x09, 0x6f, 0x76, 0x65, 0x72, 0x72, 0x69, 0x64, 0x65, 0x73,
+	0x18, 0x0e, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x64, 0x6f, 0x6f, 0x72, 0x6d, 0x61, 0x6e,
+	0x2e, 0x43, 0x61, 0x70, 0x74, 0x75, 0x72, 0x65, 0x4f, 0x76, 0x65, 0x72, 0x72, 0x69, 0x64, 0x65,
+	0x50, 0x42, 0x52, 0x09, 0x6f, 0x76, 0x65, 0x72, 0x72, 0x69, 0x64, 0x65, 0x73, 0x12, 0x2f, 0x0a,
+	0x13, 0x63, 0x61, 0x70, 0x61, 0x63, 0x69, 0x74, 0x79, 0x5f, 0x65, 0x78, 0x70, 0x72, 0x65, 0x73,
+	0x73, 0x69, 0x6f, 0x6e, 0x18, 0x0f, 0x20, 0x01, 0x28, 0x09, 0x52, 0x12, 0x63, 0x61, 0x70, 0x61,
+	0x63, 0x69, 0x74, 0x79, 0x45, 0x78, 0x70, 0x72, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x22, 0x84,
+	0x01, 0x0a, 0x11, 0x43, 0x61, 0x70, 0x74, 0x75, 0x72, 0x65, 0x4f, 0x76, 0x65, 0x72, 0x72, 0x69,
+	0x64, 0x65, 0x50, 0x42, 0x12, 0x18, 0x0a, 0x07, 0x63, 0x61, 0x70, 0x74, 0x75, 0x72, 0x65, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x63, 0x61, 0x70, 0x74, 0x75, 0x72, 0x65, 0x12, 0x14,
+	0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76,
+	0x61, 0x6c, 0x75, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x63, 0x61, 0x70, 0x61, 0x63, 0x69, 0x74, 0x79,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x08, 0x63, 0x61, 0x70, 0x61, 0x63, 0x69, 0x74, 0x79,
+	0x12, 0x23, 0x0a, 0x0d, 0x73, 0x61, 0x66, 0x65, 0x5f, 0x63, 0x61, 0x70, 0x61, 0x63, 0x69, 0x74,
+	0x79, 0x18, 0x04, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0c, 0x73, 0x61, 0x66, 0x65, 0x43, 0x61, 0x70,
+	0x61, 0x63, 0x69, 0x74, 0x79, 0x22, 0x87, 0x01, 0x0a, 0x10, 0x53, 0x63, 0x68, 0x65, 0x64, 0x75,
+	0x6c, 0x65, 0x57, 0x69, 0x6e, 0x64, 0x6f, 0x77, 0x50, 0x42, 0x12, 0x12, 0x0a, 0x04, 0x64, 0x61,
+	0x79, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x64, 0x61, 0x79, 0x73, 0x12, 0x14,
+	0x0a, 0x05, 0x73, 0x74, 0x61, 0x72, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x73,
+	0x74, 0x61, 0x72, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x65, 0x6e, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x03, 0x65, 0x6e, 0x64, 0x12, 0x1b, 0x0a, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x5f, 0x7a,
+	0x6f, 0x6e, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x74, 0x69, 0x6d, 0x65, 0x5a,
+	0x6f, 0x6e, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x63, 0x61, 0x70, 0x61, 0x63, 0x69, 0x74, 0x79, 0x18,
+	0x05, 0x20, 0x01, 0x28, 0x05, 0x52, 0x08, 0x63, 0x61, 0x70, 0x61, 0x63, 0x69, 0x74, 0x79, 0x22,
+	0x8d, 0x01, 0x0a, 0x0c, 0x43, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x52, 0x75, 0x6c, 0x65, 0x50, 0x42,
+	0x12, 0x1f, 0x0a, 0x0b, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x5f, 0x67, 0x6c, 0x6f, 0x62, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x47, 0x6c, 0x6f,
+	0x62, 0x12, 0x21, 0x0a, 0x0c, 0x6d, 0x69, 0x6e, 0x5f, 0x63, 0x61, 0x70, 0x61, 0x63, 0x69, 0x74,
+	0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0b, 0x6d, 0x69, 0x6e, 0x43, 0x61, 0x70, 0x61,
+	0x63, 0x69, 0x74, 0x79, 0x12, 0x21, 0x0a, 0x0c, 0x6d, 0x61, 0x78, 0x5f, 0x63, 0x61, 0x70, 0x61,
+	0x63, 0x69, 0x74, 0x79, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0b, 0x6d, 0x61, 0x78, 0x43,
+	0x61, 0x70, 0x61, 0x63, 0x69, 0x74, 0x79, 0x12, 0x16, 0x0a, 0x06, 0x77, 0x65, 0x69, 0x67, 0x68,
+	0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x01, 0x52, 0x06, 0x77, 0x65, 0x69, 0x67, 0x68, 0x74, 0x22,
+	0x3d, 0x0a, 0x0d, 0x43, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x47, 0x72, 0x6f, 0x75, 0x70, 0x50, 0x42,
+	0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04,
+	0x6e, 0x61, 0x6d, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x73, 0x18,
+	0x02, 0x20, 0x03, 0x28, 0x09, 0x52, 0x07, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x73, 0x22, 0x98,
+	0x01, 0x0a, 0x0b, 0x4e, 0x61, 0x6d, 0x65, 0x73, 0x70, 0x61, 0x63, 0x65, 0x50, 0x42, 0x12, 0x12,
+	0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61,
+	0x6d, 0x65, 0x12, 0x31, 0x0a, 0x09, 0x72, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x73, 0x18,
+	0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x64, 0x6f, 0x6f, 0x72, 0x6d, 0x61, 0x6e, 0x2e,
 	0x52, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x50, 0x42, 0x52, 0x09, 0x72, 0x65, 0x73, 0x6f,
-	0x75, 0x72, 0x63, 0x65, 0x73, 0x42, 0x25, 0x5a, 0x23, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e,
-	0x63, 0x6f, 0x6d, 0x2f, 0x6e, 0x6f, 0x74, 0x66, 0x72, 0x65, 0x73, 0x68, 0x2f, 0x7a, 0x78, 0x64,
-	0x6f, 0x6f, 0x72, 0x6d, 0x61, 0x6e, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x06, 0x70, 0x72,
-	0x6f, 0x74, 0x6f, 0x33,
+	0x75, 0x72, 0x63, 0x65, 0x73, 0x12, 0x1a, 0x0a, 0x08, 0x63, 0x61, 0x70, 0x61, 0x63, 0x69, 0x74,
+	0x79, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x08, 0x63, 0x61, 0x70, 0x61, 0x63, 0x69, 0x74,
+	0x79, 0x12, 0x26, 0x0a, 0x0e, 0x61, 0x64, 0x6d, 0x69, 0x6e, 0x69, 0x73, 0x74, 0x72, 0x61, 0x74,
+	0x6f, 0x72, 0x73, 0x18, 0x04, 0x20, 0x03, 0x28, 0x09, 0x52, 0x0e, 0x61, 0x64, 0x6d, 0x69, 0x6e,
+	0x69, 0x73, 0x74, 0x72, 0x61, 0x74, 0x6f, 0x72, 0x73, 0x22, 0xd5, 0x01, 0x0a, 0x12, 0x52, 0x65,
+	0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x52, 0x65, 0x70, 0x6f, 0x73, 0x69, 0x74, 0x6f, 0x72, 0x79,
+	0x12, 0x31, 0x0a, 0x09, 0x72, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x73, 0x18, 0x01, 0x20,
+	0x03, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x64, 0x6f, 0x6f, 0x72, 0x6d, 0x61, 0x6e, 0x2e, 0x52, 0x65,
+	0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x50, 0x42, 0x52, 0x09, 0x72, 0x65, 0x73, 0x6f, 0x75, 0x72,
+	0x63, 0x65, 0x73, 0x12, 0x2e, 0x0a, 0x06, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x73, 0x18, 0x02, 0x20,
+	0x03, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x64, 0x6f, 0x6f, 0x72, 0x6d, 0x61, 0x6e, 0x2e, 0x43, 0x6c,
+	0x69, 0x65, 0x6e, 0x74, 0x47, 0x72, 0x6f, 0x75, 0x70, 0x50, 0x42, 0x52, 0x06, 0x67, 0x72, 0x6f,
+	0x75, 0x70, 0x73, 0x12, 0x34, 0x0a, 0x0a, 0x6e, 0x61, 0x6d, 0x65, 0x73, 0x70, 0x61, 0x63, 0x65,
+	0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x14, 0x2e, 0x64, 0x6f, 0x6f, 0x72, 0x6d, 0x61,
+	0x6e, 0x2e, 0x4e, 0x61, 0x6d, 0x65, 0x73, 0x70, 0x61, 0x63, 0x65, 0x50, 0x42, 0x52, 0x0a, 0x6e,
+	0x61, 0x6d, 0x65, 0x73, 0x70, 0x61, 0x63, 0x65, 0x73, 0x12, 0x26, 0x0a, 0x0e, 0x61, 0x64, 0x6d,
+	0x69, 0x6e, 0x69, 0x73, 0x74, 0x72, 0x61, 0x74, 0x6f, 0x72, 0x73, 0x18, 0x04, 0x20, 0x03, 0x28,
+	0x09, 0x52, 0x0e, 0x61, 0x64, 0x6d, 0x69, 0x6e, 0x69, 0x73, 0x74, 0x72, 0x61, 0x74, 0x6f, 0x72,
+	0x73, 0x42, 0x25, 0x5a, 0x23, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f,
+	0x6e, 0x6f, 0x74, 0x66, 0x72, 0x65, 0x73, 0x68, 0x2f, 0x7a, 0x78, 0x64, 0x6f, 0x6f, 0x72, 0x6d,
+	0x61, 0x6e, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -393,24 +988,35 @@ func file_resource_proto_rawDescGZIP() []byte {
 }
 
 var file_resource_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
-var file_resource_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_resource_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
 var file_resource_proto_goTypes = []interface{}{
 	(AlgorithmPB_Kind)(0),             // 0: doorman.AlgorithmPB.Kind
 	(*AlgorithmPB)(nil),               // 1: doorman.AlgorithmPB
 	(*ResourcePB)(nil),                // 2: doorman.ResourcePB
-	(*ResourceRepository)(nil),        // 3: doorman.ResourceRepository
-	(*AlgorithmPB_NamedParamter)(nil), // 4: doorman.AlgorithmPB.NamedParamter
+	(*CaptureOverridePB)(nil),         // 3: doorman.CaptureOverridePB
+	(*ScheduleWindowPB)(nil),          // 4: doorman.ScheduleWindowPB
+	(*ClientRulePB)(nil),              // 5: doorman.ClientRulePB
+	(*ClientGroupPB)(nil),             // 6: doorman.ClientGroupPB
+	(*NamespacePB)(nil),               // 7: doorman.NamespacePB
+	(*ResourceRepository)(nil),        // 8: doorman.ResourceRepository
+	(*AlgorithmPB_NamedParamter)(nil), // 9: doorman.AlgorithmPB.NamedParamter
 }
 var file_resource_proto_depIdxs = []int32{
-	0, // 0: doorman.AlgorithmPB.kind:type_name -> doorman.AlgorithmPB.Kind
-	4, // 1: doorman.AlgorithmPB.parameters:type_name -> doorman.AlgorithmPB.NamedParamter
-	1, // 2: doorman.ResourcePB.algo:type_name -> doorman.AlgorithmPB
-	2, // 3: doorman.ResourceRepository.resources:type_name -> doorman.ResourcePB
-	4, // [4:4] is the sub-list for method output_type
-	4, // [4:4] is the sub-list for method input_type
-	4, // [4:4] is the sub-list for extension type_name
-	4, // [4:4] is the sub-list for extension extendee
-	0, // [0:4] is the sub-list for field type_name
+	0,  // 0: doorman.AlgorithmPB.kind:type_name -> doorman.AlgorithmPB.Kind
+	9,  // 1: doorman.AlgorithmPB.parameters:type_name -> doorman.AlgorithmPB.NamedParamter
+	1,  // 2: doorman.ResourcePB.algo:type_name -> doorman.AlgorithmPB
+	5,  // 3: doorman.ResourcePB.client_rules:type_name -> doorman.ClientRulePB
+	4,  // 4: doorman.ResourcePB.schedule:type_name -> doorman.ScheduleWindowPB
+	3,  // 5: doorman.ResourcePB.overrides:type_name -> doorman.CaptureOverridePB
+	2,  // 6: doorman.NamespacePB.resources:type_name -> doorman.ResourcePB
+	2,  // 7: doorman.ResourceRepository.resources:type_name -> doorman.ResourcePB
+	6,  // 8: doorman.ResourceRepository.groups:type_name -> doorman.ClientGroupPB
+	7,  // 9: doorman.ResourceRepository.namespaces:type_name -> doorman.NamespacePB
+	10, // [10:10] is the sub-list for method output_type
+	10, // [10:10] is the sub-list for method input_type
+	10, // [10:10] is the sub-list for extension type_name
+	10, // [10:10] is the sub-list for extension extendee
+	0,  // [0:10] is the sub-list for field type_name
 }
 
 func init() { file_resource_proto_init() }
@@ -444,7 +1050,7 @@ func file_resource_proto_init() {
 			}
 		}
 		file_resource_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*ResourceRepository); i {
+			switch v := v.(*CaptureOverridePB); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -456,6 +1062,66 @@ func file_resource_proto_init() {
 			}
 		}
 		file_resource_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ScheduleWindowPB); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_resource_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ClientRulePB); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_resource_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ClientGroupPB); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_resource_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*NamespacePB); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_resource_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ResourceRepository); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_resource_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*AlgorithmPB_NamedParamter); i {
 			case 0:
 				return &v.state
@@ -474,7 +1140,7 @@ func file_resource_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_resource_proto_rawDesc,
 			NumEnums:      1,
-			NumMessages:   4,
+			NumMessages:   9,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
@@ -0,0 +1,58 @@
+package doorman
+
+import (
+	"log"
+	"path/filepath"
+
+	"github.com/notfresh/zxdoorman/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// allowed returns true if client may request the resource configured
+// with cfg, groups being the client groups of the configuration.
+func allowed(cfg *proto.ResourcePB, groups []*proto.ClientGroupPB, client string) bool {
+	if len(cfg.GetAllowedClients()) == 0 && len(cfg.GetAllowedGroups()) == 0 {
+		return true
+	}
+	if matchAny(cfg.GetAllowedClients(), client) {
+		return true
+	}
+	for _, name := range cfg.GetAllowedGroups() {
+		for _, group := range groups {
+			if group.GetName() == name && matchAny(group.GetClients(), client) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchAny returns true if client matches any of globs.
+func matchAny(globs []string, client string) bool {
+	for _, glob := range globs {
+		matched, err := filepath.Match(glob, client)
+		if err != nil {
+			log.Printf("Error trying to match %v to %v", client, glob)
+			continue
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// checkAccess returns a PERMISSION_DENIED error if any of requests is
+// for a resource its client may not request.
+func (server *Server) checkAccess(requests []clientRequest) error {
+	server.mu.RLock()
+	defer server.mu.RUnlock()
+
+	for _, req := range requests {
+		if !allowed(server.findConfigForResource(req.resID), server.config.GetGroups(), req.client) {
+			return status.Errorf(codes.PermissionDenied, "client %q may not request resource %q", req.client, req.resID)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package doorman
+
+import (
+	"testing"
+	"time"
+
+	"github.com/notfresh/zxdoorman/proto"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestAccessControl(t *testing.T) {
+	algo := &proto.AlgorithmPB{LeaseLength: 60}
+	server, err := MakeTestServerWithClock(NewManualClock(time.Unix(1000000, 0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	if err := server.LoadConfig(context.Background(), &proto.ResourceRepository{
+		Resources: []*proto.ResourcePB{
+			{IdentifierGlob: "payments-*", Capacity: 100, Algo: algo, AllowedClients: []string{"payments-*"}, AllowedGroups: []string{"sre"}},
+			{IdentifierGlob: "*", Capacity: 100, Algo: algo},
+		},
+		Groups: []*proto.ClientGroupPB{
+			{Name: "sre", Clients: []string{"oncall", "sre-*"}},
+		},
+	}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		client, resource string
+		code             codes.Code
+	}{
+		{"payments-api", "payments-db", codes.OK},
+		{"sre-alice", "payments-db", codes.OK},
+		{"oncall", "payments-db", codes.OK},
+		{"search-api", "payments-db", codes.PermissionDenied},
+		{"search-api", "search-db", codes.OK},
+	} {
+		_, err := server.GetCapacity(context.Background(), &proto.GetCapacityRequest{
+			ClientId: c.client,
+			Resource: []*proto.GetCapacityRequest_ResourceRequest{
+				{ResourceId: "search-db", Want: 10},
+				{ResourceId: c.resource, Want: 10},
+			},
+		})
+		if got := status.Code(err); got != c.code {
+			t.Errorf("client %v requesting %v: code %v want %v", c.client, c.resource, got, c.code)
+		}
+	}
+
+	// No capacity was assigned to the denied client.
+	for _, lease := range server.getOrCreateResource("payments-db").Status().Leases {
+		if lease.ClientId == "search-api" {
+			t.Errorf("search-api has a lease of payments-db")
+		}
+	}
+}
@@ -1,7 +1,10 @@
 package doorman
 
 import (
+	"fmt"
 	zx "github.com/notfresh/zxdoorman/proto"
+	"log"
+	"strconv"
 	"time"
 )
 
@@ -9,32 +12,216 @@ type Request struct {
 	ClientId string
 	Has      int32
 	Want     int32
+	// Weight is the weight of the client in fair share algorithms,
+	// 0 for the default of 1.
+	Weight float64
 }
 
 type Algorithm func(store LeaseStore, capacity int, request *Request) Lease
 
 func getAlgorithmParams(algo *zx.AlgorithmPB) (leaseLength, refreshInterval time.Duration) {
-	return time.Duration(algo.GetLeaseLength()) * time.Second, time.Duration(algo.GetRefreshInterval())
+	return time.Duration(algo.GetLeaseLength()) * time.Second, time.Duration(algo.GetRefreshInterval()) * time.Second
 }
 
+// NoAlgorithm gives every client what it wants, regardless of the
+// capacity.
 // zx take a pb-defined algo and make a real function
 func NoAlgorithm(algo *zx.AlgorithmPB) Algorithm {
 	leaseLength, leaseInterval := getAlgorithmParams(algo)
 	return func(store LeaseStore, capacity int, request *Request) Lease {
-		return store.Assign(request.ClientId, leaseLength, leaseInterval, request.Has, request.Want)
+		return store.Assign(request.ClientId, leaseLength, leaseInterval, request.Want, request.Want)
 	}
 }
 
-// zx in learning mode, it does assign much than it has
+// Learn is the algorithm of resources in learning mode: clients keep
+// what they say they have, up to what they want. A client cannot
+// take more than what the others leave by claiming to have it.
 func Learn(algo *zx.AlgorithmPB) Algorithm {
 	leaseLength, leaseInterval := getAlgorithmParams(algo)
 	return func(store LeaseStore, capacity int, request *Request) Lease {
-		return store.Assign(request.ClientId, leaseLength, leaseInterval, request.Has, request.Has)
+		has := request.Has
+		if request.Want < has {
+			has = request.Want
+		}
+		old := store.Get(request.ClientId)
+		if free := int32(capacity) - (store.SumHas() - old.Has); has > free {
+			has = free
+		}
+		if has < 0 {
+			has = 0
+		}
+		return store.Assign(request.ClientId, leaseLength, leaseInterval, has, request.Want)
 	}
 }
 
+// FairShare splits the capacity between the clients in proportion to
+// their weights, never giving a client more than it wants: what a
+// client does not want is split between the others. A client never
+// gets more than what the others leave until they refresh their
+// leases and give back what is over their share.
+func FairShare(algo *zx.AlgorithmPB) Algorithm {
+	leaseLength, leaseInterval := getAlgorithmParams(algo)
+	return func(store LeaseStore, capacity int, request *Request) Lease {
+		leases := store.Map()
+		old := leases[request.ClientId]
+		leases[request.ClientId] = Lease{Want: request.Want, Weight: request.Weight}
+
+		share := fairShares(leases, capacity)[request.ClientId]
+		if free := int32(capacity) - (store.SumHas() - old.Has); share > free {
+			share = free
+		}
+		if share < 0 {
+			share = 0
+		}
+		lease := store.Assign(request.ClientId, leaseLength, leaseInterval, share, request.Want)
+		store.SetWeight(request.ClientId, request.Weight)
+		lease.Weight = request.Weight
+		return lease
+	}
+}
+
+// fairShares splits capacity between the clients of leases in
+// proportion to their weights, capped at their wants, and returns
+// the shares by client id. It fills the smaller wants first and
+// splits what they leave between the other clients, until every
+// client gets what it wants or the capacity is used up.
+func fairShares(leases map[string]Lease, capacity int) map[string]int32 {
+	shares := make(map[string]int32, len(leases))
+	active := make([]string, 0, len(leases))
+	for id := range leases {
+		active = append(active, id)
+	}
+	remaining := float64(capacity)
+	for len(active) > 0 {
+		var sumWeight float64
+		for _, id := range active {
+			sumWeight += weight(leases[id])
+		}
+		var unsatisfied []string
+		var given float64
+		for _, id := range active {
+			lease := leases[id]
+			if float64(lease.Want) <= remaining*weight(lease)/sumWeight {
+				shares[id] = lease.Want
+				given += float64(lease.Want)
+			} else {
+				unsatisfied = append(unsatisfied, id)
+			}
+		}
+		if len(unsatisfied) == len(active) {
+			for _, id := range active {
+				shares[id] = int32(remaining * weight(leases[id]) / sumWeight)
+			}
+			break
+		}
+		remaining -= given
+		active = unsatisfied
+	}
+	return shares
+}
+
+// weight returns the weight of the client holding lease.
+func weight(lease Lease) float64 {
+	if lease.Weight <= 0 {
+		return 1
+	}
+	return lease.Weight
+}
+
+// burstSizeParam is the name of the parameter of TokenBucket giving
+// the maximum burst of a client.
+const burstSizeParam = "burst_size"
+
+// TokenBucket gives clients a steady rate, their share of the capacity
+// in proportion to their weights, whatever they want, and a burst: what
+// they did not use of their rate, accumulated between their requests up
+// to the burst_size parameter. Clients wanting more than their rate use
+// their burst up. As the burst only comes from unused capacity, the
+// clients never use more than the capacity in the long run.
+func TokenBucket(algo *zx.AlgorithmPB) Algorithm {
+	leaseLength, leaseInterval := getAlgorithmParams(algo)
+	burstSize, err := intParam(algo, burstSizeParam)
+	if err != nil {
+		log.Printf("%v, bursts are disabled", err)
+	}
+	return func(store LeaseStore, capacity int, request *Request) Lease {
+		old := store.Get(request.ClientId)
+		sumWeight := weight(Lease{Weight: request.Weight})
+		for id, lease := range store.Map() {
+			if id != request.ClientId {
+				sumWeight += weight(lease)
+			}
+		}
+		rate := int32(float64(capacity) * weight(Lease{Weight: request.Weight}) / sumWeight)
+		if free := int32(capacity) - (store.SumHas() - old.Has); rate > free {
+			rate = free
+		}
+		if rate < 0 {
+			rate = 0
+		}
+
+		lease := store.Assign(request.ClientId, leaseLength, leaseInterval, rate, request.Want)
+		// Computed in float64, as large capacities or long gaps
+		// between refreshes overflow an int32 before the clamping.
+		burst := float64(old.Burst)
+		if !old.IsZero() {
+			// The client used its old lease, wanting old.Want,
+			// since it was assigned.
+			elapsed := lease.ExpireTime.Sub(old.ExpireTime).Seconds()
+			burst += float64(old.Has-old.Want) * elapsed
+		}
+		if burst > float64(burstSize) {
+			burst = float64(burstSize)
+		}
+		if burst < 0 {
+			burst = 0
+		}
+		store.SetWeight(request.ClientId, request.Weight)
+		store.SetBurst(request.ClientId, int32(burst))
+		lease.Weight, lease.Burst = request.Weight, int32(burst)
+		return lease
+	}
+}
+
+// intParam returns the value of the integer parameter name of algo, 0
+// if it is not set.
+func intParam(algo *zx.AlgorithmPB, name string) (int32, error) {
+	for _, param := range algo.GetParameters() {
+		if param.GetName() != name {
+			continue
+		}
+		v, err := strconv.ParseInt(param.GetValue(), 10, 32)
+		if err != nil || v < 0 {
+			return 0, fmt.Errorf("bad %v parameter %q", name, param.GetValue())
+		}
+		return int32(v), nil
+	}
+	return 0, nil
+}
+
 type algoMapperFunc func(pb *zx.AlgorithmPB) Algorithm
 
 var algoMapper = map[zx.AlgorithmPB_Kind]algoMapperFunc{
 	zx.AlgorithmPB_NO_ALGORITHM: NoAlgorithm,
+	zx.AlgorithmPB_FAIR:         FairShare,
+	zx.AlgorithmPB_TOKEN_BUCKET: TokenBucket,
+}
+
+// algorithmFor returns the algorithm configured by algo. Kinds
+// without an implementation give no capacity at all, rather than
+// crashing the server.
+func algorithmFor(algo *zx.AlgorithmPB) Algorithm {
+	if mapper, ok := algoMapper[algo.GetKind()]; ok {
+		return mapper(algo)
+	}
+	log.Printf("algorithm %v is not implemented, no capacity will be given", algo.GetKind())
+	return noCapacity(algo)
+}
+
+// noCapacity returns an algorithm that gives nothing.
+func noCapacity(algo *zx.AlgorithmPB) Algorithm {
+	leaseLength, leaseInterval := getAlgorithmParams(algo)
+	return func(store LeaseStore, capacity int, request *Request) Lease {
+		return store.Assign(request.ClientId, leaseLength, leaseInterval, 0, request.Want)
+	}
 }
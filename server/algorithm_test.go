@@ -0,0 +1,192 @@
+package doorman
+
+import (
+	"testing"
+	"time"
+
+	"github.com/notfresh/zxdoorman/proto"
+	"golang.org/x/net/context"
+)
+
+func TestNoAlgorithm(t *testing.T) {
+	store := NewLeaseStore("test", NewManualClock(time.Unix(0, 0)))
+	algo := NoAlgorithm(&proto.AlgorithmPB{LeaseLength: 60, RefreshInterval: 5})
+
+	lease := algo(store, 100, &Request{ClientId: "c1", Has: 10, Want: 200})
+	if want, got := int32(200), lease.Has; want != got {
+		t.Errorf("lease.Has = %v want %v", got, want)
+	}
+	if want, got := 5*time.Second, lease.RefreshInterval; want != got {
+		t.Errorf("lease.RefreshInterval = %v want %v", got, want)
+	}
+}
+
+func TestUnimplementedAlgorithm(t *testing.T) {
+	store := NewLeaseStore("test", NewManualClock(time.Unix(0, 0)))
+	algo := algorithmFor(&proto.AlgorithmPB{Kind: proto.AlgorithmPB_Kind(42), LeaseLength: 60})
+
+	lease := algo(store, 100, &Request{ClientId: "c1", Has: 10, Want: 20})
+	if lease.Has != 0 || lease.Want != 20 {
+		t.Errorf("lease = %+v, want nothing granted", lease)
+	}
+}
+
+func TestFairShares(t *testing.T) {
+	shares := fairShares(map[string]Lease{
+		"a": {Want: 100},
+		"b": {Want: 100, Weight: 3},
+		"c": {Want: 10},
+	}, 100)
+	// c wants less than its share, a and b split the rest 1 to 3.
+	for id, want := range map[string]int32{"a": 22, "b": 67, "c": 10} {
+		if shares[id] != want {
+			t.Errorf("shares[%v] = %v want %v", id, shares[id], want)
+		}
+	}
+}
+
+func TestFairShare(t *testing.T) {
+	store := NewLeaseStore("test", NewManualClock(time.Unix(0, 0)))
+	algo := FairShare(&proto.AlgorithmPB{Kind: proto.AlgorithmPB_FAIR, LeaseLength: 60})
+
+	for _, c := range []struct {
+		client string
+		weight float64
+		gets   int32
+	}{
+		{"a", 0, 100},
+		// a holds everything until it refreshes.
+		{"b", 3, 0},
+		{"a", 0, 25},
+		{"b", 3, 75},
+	} {
+		lease := algo(store, 100, &Request{ClientId: c.client, Want: 100, Weight: c.weight})
+		if lease.Has != c.gets {
+			t.Errorf("%v got %v want %v", c.client, lease.Has, c.gets)
+		}
+	}
+	if got := store.Get("b").Weight; got != 3 {
+		t.Errorf("b has weight %v want 3", got)
+	}
+}
+
+func TestFairShareRuleWeights(t *testing.T) {
+	clock := NewManualClock(time.Unix(1000000, 0))
+	server, err := MakeTestServerWithClock(clock, &proto.ResourcePB{
+		IdentifierGlob: "*",
+		Capacity:       90,
+		Algo:           &proto.AlgorithmPB{Kind: proto.AlgorithmPB_FAIR, LeaseLength: 60},
+		ClientRules:    []*proto.ClientRulePB{{ClientGlob: "big-*", Weight: 2}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	// Gets out of learning mode.
+	clock.Advance(2 * time.Minute)
+
+	getCapacity(t, server, "small", "res", 0, 100)
+	getCapacity(t, server, "big-1", "res", 0, 100)
+	if got := getCapacity(t, server, "small", "res", 0, 100).GetGets().GetCapacity(); got != 30 {
+		t.Errorf("small got %v want 30", got)
+	}
+	if got := getCapacity(t, server, "big-1", "res", 0, 100).GetGets().GetCapacity(); got != 60 {
+		t.Errorf("big-1 got %v want 60", got)
+	}
+}
+
+func TestFairShareRequestWeights(t *testing.T) {
+	clock := NewManualClock(time.Unix(1000000, 0))
+	server, err := MakeTestServerWithClock(clock, &proto.ResourcePB{
+		IdentifierGlob: "*",
+		Capacity:       90,
+		Algo:           &proto.AlgorithmPB{Kind: proto.AlgorithmPB_FAIR, LeaseLength: 60},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	client, stop := serve(t, server, nil)
+	defer stop()
+	// Gets out of learning mode.
+	clock.Advance(2 * time.Minute)
+
+	get := func(id string, weight float64) int32 {
+		out, err := client.GetCapacity(context.Background(), &proto.GetCapacityRequest{
+			ClientId: id,
+			Resource: []*proto.GetCapacityRequest_ResourceRequest{{
+				ResourceId: "res",
+				Want:       100,
+				Weight:     weight,
+			}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out.GetResponse()[0].GetGets().GetCapacity()
+	}
+	get("light", 1)
+	get("heavy", 2)
+	if got := get("light", 1); got != 30 {
+		t.Errorf("light got %v want 30", got)
+	}
+	if got := get("heavy", 2); got != 60 {
+		t.Errorf("heavy got %v want 60", got)
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	clock := NewManualClock(time.Unix(0, 0))
+	store := NewLeaseStore("test", clock)
+	algo := TokenBucket(&proto.AlgorithmPB{
+		Kind:        proto.AlgorithmPB_TOKEN_BUCKET,
+		LeaseLength: 60,
+		Parameters:  []*proto.AlgorithmPB_NamedParamter{{Name: "burst_size", Value: "300"}},
+	})
+
+	for _, c := range []struct {
+		advance    time.Duration
+		client     string
+		want       int32
+		has, burst int32
+	}{
+		// a gets the whole capacity as rate, whatever it wants.
+		{0, "a", 20, 100, 0},
+		// It used 20 of 100 for 2 seconds.
+		{2 * time.Second, "a", 20, 100, 160},
+		// The burst is capped.
+		{2 * time.Second, "a", 20, 100, 300},
+		// b only gets its rate once a gave it back.
+		{0, "b", 50, 0, 0},
+		{0, "a", 20, 50, 300},
+		{0, "b", 50, 50, 0},
+		// a bursting uses its burst up.
+		{0, "a", 250, 50, 300},
+		{time.Second, "a", 250, 50, 100},
+		{time.Second, "a", 250, 50, 0},
+	} {
+		clock.Advance(c.advance)
+		lease := algo(store, 100, &Request{ClientId: c.client, Want: c.want})
+		if lease.Has != c.has || lease.Burst != c.burst {
+			t.Errorf("%v wanting %v got %v with burst %v, want %v with burst %v", c.client, c.want, lease.Has, lease.Burst, c.has, c.burst)
+		}
+	}
+}
+
+func TestTokenBucketLargeBurst(t *testing.T) {
+	clock := NewManualClock(time.Unix(0, 0))
+	store := NewLeaseStore("test", clock)
+	algo := TokenBucket(&proto.AlgorithmPB{
+		Kind:        proto.AlgorithmPB_TOKEN_BUCKET,
+		LeaseLength: 3600,
+		Parameters:  []*proto.AlgorithmPB_NamedParamter{{Name: "burst_size", Value: "1000000000"}},
+	})
+
+	// An hour of 2e9 unused is far more than an int32: the burst is
+	// still capped at its size.
+	algo(store, 2000000000, &Request{ClientId: "a"})
+	clock.Advance(time.Hour)
+	if lease := algo(store, 2000000000, &Request{ClientId: "a"}); lease.Burst != 1000000000 {
+		t.Errorf("burst %v want 1000000000", lease.Burst)
+	}
+}
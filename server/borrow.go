@@ -0,0 +1,167 @@
+package doorman
+
+import "sync"
+
+// borrowGroup is the resources of a borrowing group, which lend each
+// other the capacity they do not want. The members report their usage
+// to the group rather than the group reading it from them, so that
+// members never need each other's locks. A member holds g.mu while it
+// decides, taken after its own lock and those of its parent and
+// namespace.
+type borrowGroup struct {
+	name    string
+	mu      sync.Mutex
+	members map[string]memberUsage
+}
+
+// memberUsage is the usage of a member of a borrowing group.
+type memberUsage struct {
+	capacity, has, want int32
+}
+
+// unused returns what the member can lend.
+func (u memberUsage) unused() int32 {
+	used := u.want
+	if u.has > used {
+		used = u.has
+	}
+	if used >= u.capacity {
+		return 0
+	}
+	return u.capacity - used
+}
+
+// borrowed returns what the member has on top of its capacity.
+func (u memberUsage) borrowed() int32 {
+	if u.has <= u.capacity {
+		return 0
+	}
+	return u.has - u.capacity
+}
+
+func newBorrowGroup(name string) *borrowGroup {
+	return &borrowGroup{name: name, members: make(map[string]memberUsage)}
+}
+
+// borrowable returns how much the member id can borrow: what the
+// other members do not want, less what they borrowed themselves. It
+// is negative while the other members still have some of the
+// capacity of id, which it only gets back as they refresh. g.mu must
+// be held.
+func (g *borrowGroup) borrowable(id string) int32 {
+	var unused, borrowed, free int32
+	for other, usage := range g.members {
+		if other == id {
+			continue
+		}
+		unused += usage.unused()
+		borrowed += usage.borrowed()
+		free += usage.capacity - usage.has
+	}
+	borrowable := unused - borrowed
+	if borrowable < 0 {
+		borrowable = 0
+	}
+	if free < borrowable {
+		borrowable = free
+	}
+	return borrowable
+}
+
+// lent returns how much of what the member id has, with the given
+// usage, the other members lent it: what it has on top of its
+// capacity, up to the capacity the others leave free. The rest, if
+// any, is left over from a capacity decrease.
+func (g *borrowGroup) lent(id string, usage memberUsage) int32 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var free int32
+	for other, u := range g.members {
+		if other != id && u.capacity > u.has {
+			free += u.capacity - u.has
+		}
+	}
+	if lent := usage.borrowed(); lent < free {
+		return lent
+	}
+	return free
+}
+
+// record records the usage of the member id. g.mu must be held.
+func (g *borrowGroup) record(id string, usage memberUsage) {
+	g.members[id] = usage
+}
+
+// update does the work of record, taking g.mu.
+func (g *borrowGroup) update(id string, usage memberUsage) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.record(id, usage)
+}
+
+// remove forgets the member id.
+func (g *borrowGroup) remove(id string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.members, id)
+}
+
+// setGroup makes the resource a member of the borrowing group g, or
+// of none if it is nil.
+func (res *Resource) setGroup(g *borrowGroup) {
+	res.mu.Lock()
+	defer res.mu.Unlock()
+	if res.group == g {
+		return
+	}
+	if res.group != nil {
+		res.group.remove(res.resourceId)
+	}
+	res.group = g
+	res.reportUsage()
+}
+
+// groupName returns the name of the borrowing group of the resource,
+// "" if it has none. res.mu must be held.
+func (res *Resource) groupName() string {
+	if res.group == nil {
+		return ""
+	}
+	return res.group.name
+}
+
+// reportUsage records the usage of the resource in its borrowing
+// group and its namespace, if it has them. res.mu must be held.
+func (res *Resource) reportUsage() {
+	if ns := res.quota(); ns != nil {
+		ns.update(res.resourceId, res.store.SumHas())
+	}
+	if res.group == nil {
+		return
+	}
+	res.group.update(res.resourceId, res.usage())
+}
+
+// usage returns the usage of the resource as a member of a borrowing
+// group. res.mu must be held.
+func (res *Resource) usage() memberUsage {
+	return memberUsage{
+		capacity: int32(res.Capacity()),
+		has:      res.store.SumHas(),
+		want:     res.store.SumWant(),
+	}
+}
+
+// borrowGroup returns the borrowing group named name, creating it if
+// necessary, or nil if name is empty. server.mu must be held.
+func (server *Server) borrowGroup(name string) *borrowGroup {
+	if name == "" {
+		return nil
+	}
+	g, ok := server.groups[name]
+	if !ok {
+		g = newBorrowGroup(name)
+		server.groups[name] = g
+	}
+	return g
+}
@@ -0,0 +1,130 @@
+package doorman
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/notfresh/zxdoorman/proto"
+)
+
+func TestBorrowing(t *testing.T) {
+	clock := NewManualClock(time.Unix(1000000, 0))
+	algo := &proto.AlgorithmPB{Kind: proto.AlgorithmPB_FAIR, LeaseLength: 60}
+	server, err := MakeTestServerWithClock(clock,
+		&proto.ResourcePB{IdentifierGlob: "a", Capacity: 100, Algo: algo, BorrowingGroup: "pool"},
+		&proto.ResourcePB{IdentifierGlob: "b", Capacity: 100, Algo: algo, BorrowingGroup: "pool"},
+		&proto.ResourcePB{IdentifierGlob: "c", Capacity: 100, Algo: algo},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	// Gets out of learning mode.
+	clock.Advance(2 * time.Minute)
+	server.getOrCreateResource("b")
+	server.getOrCreateResource("c")
+
+	// x borrows what b does not want, but nothing from c which is
+	// not in the group.
+	if got := getCapacity(t, server, "x", "a", 0, 150).GetGets().GetCapacity(); got != 150 {
+		t.Errorf("x got %v, want 150", got)
+	}
+	if status := server.getOrCreateResource("a").Status(); status.Group != "pool" || status.Borrowed != 50 {
+		t.Errorf("a is in group %q and borrowed %v, want pool and 50", status.Group, status.Borrowed)
+	}
+
+	// b wants its capacity back: it only gets what x did not borrow
+	// until x gives back the rest at its next refresh.
+	if got := getCapacity(t, server, "y", "b", 0, 100).GetGets().GetCapacity(); got != 50 {
+		t.Errorf("y got %v while x still borrows, want 50", got)
+	}
+	if got := getCapacity(t, server, "x", "a", 150, 150).GetGets().GetCapacity(); got != 100 {
+		t.Errorf("x got %v after b's demand returned, want 100", got)
+	}
+	if got := getCapacity(t, server, "y", "b", 50, 100).GetGets().GetCapacity(); got != 100 {
+		t.Errorf("y got %v once x gave back, want 100", got)
+	}
+	var total int32
+	for _, id := range []string{"a", "b"} {
+		total += server.getOrCreateResource(id).Status().SumHas
+	}
+	if total > 200 {
+		t.Errorf("the group gave out %v, more than its capacity 200", total)
+	}
+}
+
+func TestBorrowedOvercommitted(t *testing.T) {
+	clock := NewManualClock(time.Unix(1000000, 0))
+	algo := &proto.AlgorithmPB{Kind: proto.AlgorithmPB_FAIR, LeaseLength: 60}
+	repository := func(capacity int32) *proto.ResourceRepository {
+		return &proto.ResourceRepository{Resources: []*proto.ResourcePB{
+			{IdentifierGlob: "a", Capacity: capacity, Algo: algo, BorrowingGroup: "pool"},
+			{IdentifierGlob: "b", Capacity: 100, Algo: algo, BorrowingGroup: "pool"},
+		}}
+	}
+	server, err := MakeTestServerWithClock(clock, repository(100).Resources...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	// Gets out of learning mode.
+	clock.Advance(2 * time.Minute)
+
+	getCapacity(t, server, "x", "a", 0, 100)
+	getCapacity(t, server, "y", "b", 0, 100)
+	if err := server.LoadConfig(context.Background(), repository(50), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// a has 50 over its capacity, but b lent it nothing.
+	if status := server.getOrCreateResource("a").Status(); !status.Overcommitted || status.Borrowed != 0 {
+		t.Errorf("a overcommitted %v and borrowed %v, want true and 0", status.Overcommitted, status.Borrowed)
+	}
+}
+
+func TestBorrowingConcurrently(t *testing.T) {
+	const members, rounds = 32, 20
+	algo := &proto.AlgorithmPB{Kind: proto.AlgorithmPB_FAIR, LeaseLength: 60}
+	for round := 0; round < rounds; round++ {
+		clock := NewManualClock(time.Unix(1000000, 0))
+		server, err := MakeTestServerWithClock(clock,
+			&proto.ResourcePB{IdentifierGlob: "*", Capacity: 10, Algo: algo, BorrowingGroup: "pool"},
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Gets out of learning mode.
+		clock.Advance(2 * time.Minute)
+		var resources []*Resource
+		for i := 0; i < members; i++ {
+			resources = append(resources, server.getOrCreateResource(fmt.Sprint("r", i)))
+		}
+
+		// Every member wants the capacity of the whole group at once:
+		// they must not lend each other the same capacity.
+		var wg sync.WaitGroup
+		start := make(chan struct{})
+		for i, res := range resources {
+			wg.Add(1)
+			go func(i int, res *Resource) {
+				defer wg.Done()
+				<-start
+				res.Decide(&Request{ClientId: fmt.Sprint("c", i), Want: 10 * members})
+			}(i, res)
+		}
+		close(start)
+		wg.Wait()
+
+		var total int32
+		for _, res := range resources {
+			total += res.Status().SumHas
+		}
+		server.Close()
+		if total > 10*members {
+			t.Fatalf("round %v: the group gave out %v, more than its capacity %v", round, total, 10*members)
+		}
+	}
+}
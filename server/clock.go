@@ -0,0 +1,183 @@
+package doorman
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells the time to a server, its resources and their lease
+// stores. Servers use the real clock unless created with WithClock.
+type Clock interface {
+	Now() time.Time
+	// After waits for d to elapse and then sends the current time
+	// on the returned channel.
+	After(d time.Duration) <-chan time.Time
+	// NewTimer returns a Timer sending the current time on its
+	// channel once d has elapsed.
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer is a timer of a Clock, which unlike After can be stopped and
+// reused.
+type Timer interface {
+	C() <-chan time.Time
+	// Stop stops the timer. It returns false if the timer already
+	// fired or was stopped.
+	Stop() bool
+	// Reset makes the timer fire once d has elapsed, whether or not
+	// it fired already. A time it sent and nobody received is
+	// dropped.
+	Reset(d time.Duration)
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+type realTimer struct {
+	*time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
+
+func (t realTimer) Reset(d time.Duration) {
+	if !t.Timer.Stop() {
+		select {
+		case <-t.Timer.C:
+		default:
+		}
+	}
+	t.Timer.Reset(d)
+}
+
+// WithClock makes the server use clock instead of the real time, for
+// example to replay or simulate traffic faster than real time.
+func WithClock(clock Clock) ServerOption {
+	return func(server *Server) {
+		server.clock = clock
+	}
+}
+
+// ManualClock is a Clock whose time only changes when told so. It
+// is used to simulate time and to make tests fast and
+// deterministic.
+type ManualClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*manualTimer
+}
+
+// NewManualClock returns a ManualClock set to now.
+func NewManualClock(now time.Time) *ManualClock {
+	return &ManualClock{now: now}
+}
+
+func (c *ManualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Set sets the time to now. The time cannot go backwards: an earlier
+// now is ignored.
+func (c *ManualClock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if now.After(c.now) {
+		c.now = now
+		c.wake()
+	}
+}
+
+// Advance moves the time forward by d.
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if d > 0 {
+		c.now = c.now.Add(d)
+		c.wake()
+	}
+}
+
+// After returns a channel on which the time is sent once the clock
+// has been moved forward by d.
+func (c *ManualClock) After(d time.Duration) <-chan time.Time {
+	return c.NewTimer(d).C()
+}
+
+// NewTimer returns a Timer firing once the clock has been moved
+// forward by d.
+func (c *ManualClock) NewTimer(d time.Duration) Timer {
+	t := &manualTimer{clock: c, c: make(chan time.Time, 1)}
+	t.Reset(d)
+	return t
+}
+
+// wake fires the timers whose time has come. c.mu must be held.
+func (c *ManualClock) wake() {
+	timers := c.timers[:0]
+	for _, t := range c.timers {
+		if t.at.After(c.now) {
+			timers = append(timers, t)
+			continue
+		}
+		t.c <- c.now
+	}
+	c.timers = timers
+}
+
+// remove stops t if it is pending. c.mu must be held.
+func (c *ManualClock) remove(t *manualTimer) bool {
+	for i, other := range c.timers {
+		if other == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+type manualTimer struct {
+	clock *ManualClock
+	at    time.Time
+	c     chan time.Time
+}
+
+func (t *manualTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *manualTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	return t.clock.remove(t)
+}
+
+func (t *manualTimer) Reset(d time.Duration) {
+	c := t.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.remove(t)
+	select {
+	case <-t.c:
+	default:
+	}
+	if d <= 0 {
+		t.c <- c.now
+		return
+	}
+	t.at = c.now.Add(d)
+	c.timers = append(c.timers, t)
+}
@@ -0,0 +1,123 @@
+package doorman
+
+import (
+	"testing"
+	"time"
+)
+
+func TestManualClock(t *testing.T) {
+	start := time.Unix(1000, 0)
+	clock := NewManualClock(start)
+	after := clock.After(10 * time.Second)
+
+	clock.Advance(5 * time.Second)
+	select {
+	case <-after:
+		t.Fatalf("After(10s) fired after 5s")
+	default:
+	}
+
+	clock.Set(start)
+	if want, got := start.Add(5*time.Second), clock.Now(); !want.Equal(got) {
+		t.Errorf("Now() = %v after setting it backwards, want %v", got, want)
+	}
+
+	clock.Set(start.Add(10 * time.Second))
+	select {
+	case now := <-after:
+		if want := start.Add(10 * time.Second); !now.Equal(want) {
+			t.Errorf("After(10s) sent %v want %v", now, want)
+		}
+	default:
+		t.Fatalf("After(10s) did not fire after 10s")
+	}
+}
+
+func TestRunEvictsWithClock(t *testing.T) {
+	clock := NewManualClock(time.Unix(1000000, 0))
+	server, err := MakeTestServerWithClock(clock, repositoryWithCapacity(100).Resources...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	server.getOrCreateResource("idle")
+
+	// The run loop evicts on the simulated time, not the real one.
+	for i := 0; i < 1000; i++ {
+		clock.Advance(defaultResourceIdleTime)
+		server.mu.RLock()
+		n := len(server.resources)
+		server.mu.RUnlock()
+		if n == 0 {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Errorf("idle resource was not evicted")
+}
+
+func TestResourceIdleTime(t *testing.T) {
+	clock := NewManualClock(time.Unix(1000000, 0))
+	for _, c := range []struct {
+		idleTime time.Duration
+		evicted  bool
+	}{
+		{time.Minute, true},
+		{time.Hour, false},
+		{0, false},
+	} {
+		server, err := makeTestServer("test", "", []ServerOption{WithClock(clock), WithResourceIdleTime(c.idleTime)}, repositoryWithCapacity(100).Resources)
+		if err != nil {
+			t.Fatal(err)
+		}
+		server.getOrCreateResource("idle")
+		server.evictIdleResources(clock.Now().Add(2 * time.Minute))
+		server.mu.RLock()
+		_, ok := server.resources["idle"]
+		server.mu.RUnlock()
+		if ok == c.evicted {
+			t.Errorf("idle time %v: evicted %v, want %v", c.idleTime, !ok, c.evicted)
+		}
+		server.Close()
+	}
+}
+
+func TestManualTimer(t *testing.T) {
+	start := time.Unix(1000, 0)
+	clock := NewManualClock(start)
+	timer := clock.NewTimer(10 * time.Second)
+	for i := 0; i < 10; i++ {
+		timer.Reset(10 * time.Second)
+	}
+	if n := len(clock.timers); n != 1 {
+		t.Errorf("%v timers pending after resetting one, want 1", n)
+	}
+
+	clock.Advance(10 * time.Second)
+	timer.Reset(5 * time.Second)
+	select {
+	case <-timer.C():
+		t.Fatalf("Reset(5s) did not drop the time sent before it")
+	default:
+	}
+	clock.Advance(5 * time.Second)
+	select {
+	case now := <-timer.C():
+		if want := start.Add(15 * time.Second); !now.Equal(want) {
+			t.Errorf("timer sent %v want %v", now, want)
+		}
+	default:
+		t.Fatalf("timer did not fire after a reset of 5s")
+	}
+
+	timer.Reset(time.Second)
+	if !timer.Stop() {
+		t.Errorf("Stop() = false for a pending timer")
+	}
+	if timer.Stop() {
+		t.Errorf("Stop() = true for a stopped timer")
+	}
+	if n := len(clock.timers); n != 0 {
+		t.Errorf("%v timers pending after stopping, want 0", n)
+	}
+}
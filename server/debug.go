@@ -0,0 +1,119 @@
+package doorman
+
+import (
+	"encoding/json"
+	"html/template"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var statusTemplate = template.Must(template.New("status").Funcs(template.FuncMap{
+	"time": func(t time.Time) string {
+		if t.IsZero() || t.Unix() == 0 {
+			return "-"
+		}
+		return t.Format(time.RFC3339)
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<title>doorman {{.ID}}</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+th, td { border: 1px solid #ccc; padding: 2px 8px; text-align: left; }
+</style>
+</head>
+<body>
+<h1>doorman {{.ID}}</h1>
+<p>
+{{if .IsMaster}}Master since {{time .BecameMasterAt}}.{{else}}Not the master.{{end}}
+Configuration version {{.ConfigVersion}} loaded at {{time .ConfigLoadedAt}}.
+<a href="status.json{{if .Namespace}}?namespace={{.Namespace}}{{end}}">JSON</a>
+</p>
+{{if .Namespaces}}
+<table>
+<tr><th>Namespace</th><th>Capacity</th><th>Has</th></tr>
+{{range .Namespaces}}
+<tr><td><a href="?namespace={{.Name}}">{{.Name}}</a></td><td>{{if .Capacity}}{{.Capacity}}{{else}}unlimited{{end}}</td><td>{{.Has}}</td></tr>
+{{end}}
+</table>
+{{end}}
+{{range .Resources}}
+<h2 id="{{.ID}}">{{.ID}}</h2>
+<table>
+{{if .Namespace}}<tr><th>Namespace</th><td><a href="?namespace={{.Namespace}}">{{.Namespace}}</a></td></tr>{{end}}
+<tr><th>Matched config</th><td>{{if .Config}}{{.Config.IdentifierGlob}}{{else}}none{{end}}</td></tr>
+{{if .Group}}<tr><th>Borrowing group</th><td>{{.Group}}{{if .Borrowed}} (borrowed {{.Borrowed}}){{end}}</td></tr>{{end}}
+{{if .Parent}}<tr><th>Parent</th><td><a href="#{{.Parent}}">{{.Parent}}</a></td></tr>{{end}}
+<tr><th>Algorithm</th><td>{{.Config.GetAlgo.GetKind}}</td></tr>
+<tr><th>Capacity</th><td>{{.Capacity}}</td></tr>
+<tr><th>Sum has</th><td>{{.SumHas}}</td></tr>
+<tr><th>Sum want</th><td>{{.SumWant}}</td></tr>
+<tr><th>Clients</th><td>{{.Count}}</td></tr>
+<tr><th>Learning mode ends</th><td>{{time .LearningEndAt}}{{if .InLearningMode}} (in learning mode){{end}}</td></tr>
+<tr><th>Expires</th><td>{{time .ExpiryTime}}{{if .Expired}} (expired){{end}}</td></tr>
+{{if .Overcommitted}}<tr><th>Overcommitted until</th><td>{{time .OvercommitUntil}} (only free capacity is given out)</td></tr>{{end}}
+</table>
+{{if .Leases}}
+<table>
+<tr><th>Client</th><th>Has</th><th>Want</th><th>Weight</th><th>Burst</th><th>Expires</th><th>Refresh interval</th></tr>
+{{range .Leases}}
+<tr><td>{{.ClientId}}</td><td>{{.Has}}</td><td>{{.Want}}</td><td>{{if .Weight}}{{.Weight}}{{end}}</td><td>{{if .Burst}}{{.Burst}}{{end}}</td><td>{{time .ExpireTime}}</td><td>{{.RefreshInterval}}</td></tr>
+{{end}}
+</table>
+{{end}}
+{{else}}
+<p>No resources have been requested yet.</p>
+{{end}}
+</body>
+</html>
+`))
+
+// DebugHandler returns the handler of the debug HTTP server. It
+// serves the status of the server and its resources as HTML on
+// /debug/status and as JSON on /debug/status.json, and Prometheus
+// metrics on /metrics. The namespace parameter of the status pages
+// restricts them to a namespace.
+func (server *Server) DebugHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(server.metrics.registry, promhttp.HandlerOpts{}))
+	mux.HandleFunc("/debug/status", server.serveStatus)
+	mux.HandleFunc("/debug/status.json", server.serveStatusJSON)
+	mux.Handle("/", http.RedirectHandler("/debug/status", http.StatusFound))
+	return mux
+}
+
+func (server *Server) serveStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	status := struct {
+		ServerStatus
+		// Namespace is the namespace the page is restricted to.
+		Namespace string
+	}{server.statusFor(r), r.FormValue("namespace")}
+	if err := statusTemplate.Execute(w, status); err != nil {
+		log.Printf("cannot render status page: %v", err)
+	}
+}
+
+func (server *Server) serveStatusJSON(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(server.statusFor(r)); err != nil {
+		log.Printf("cannot encode status: %v", err)
+	}
+}
+
+// statusFor returns the status of the server, restricted to the
+// namespace parameter of r if there is one.
+func (server *Server) statusFor(r *http.Request) ServerStatus {
+	status := server.Status()
+	if name := r.FormValue("namespace"); name != "" {
+		return status.InNamespace(name)
+	}
+	return status
+}
@@ -0,0 +1,70 @@
+package doorman
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/notfresh/zxdoorman/proto"
+)
+
+func TestDebugStatus(t *testing.T) {
+	server, err := MakeTestServer(&proto.ResourcePB{
+		IdentifierGlob: "res*",
+		Capacity:       100,
+		Algo:           &proto.AlgorithmPB{LeaseLength: 60, RefreshInterval: 5},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	getCapacity(t, server, "client1", "res1", 10, 20)
+	getCapacity(t, server, "client2", "res1", 5, 5)
+
+	ts := httptest.NewServer(server.DebugHandler())
+	defer ts.Close()
+
+	resp, err := ts.Client().Get(ts.URL + "/debug/status.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var status ServerStatus
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		t.Fatal(err)
+	}
+	if want, got := 1, len(status.Resources); want != got {
+		t.Fatalf("len(resources) = %v want %v", got, want)
+	}
+	res := status.Resources[0]
+	if res.ID != "res1" || res.Config.GetIdentifierGlob() != "res*" || res.Capacity != 100 {
+		t.Errorf("resource = %+v, want res1 matching res* with capacity 100", res)
+	}
+	if res.SumHas != 15 || res.Count != 2 {
+		t.Errorf("resource has %v with %v clients, want 15 with 2", res.SumHas, res.Count)
+	}
+	if !res.InLearningMode {
+		t.Errorf("resource not in learning mode")
+	}
+	if len(res.Leases) != 2 || res.Leases[0].ClientId != "client1" || res.Leases[0].Has != 10 {
+		t.Errorf("leases = %+v", res.Leases)
+	}
+
+	resp, err = ts.Client().Get(ts.URL + "/debug/status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	page, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"res1", "res*", "client1", "client2"} {
+		if !strings.Contains(string(page), want) {
+			t.Errorf("status page does not contain %q", want)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package doorman
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"math/rand"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// DecisionLogger writes a JSON line for capacity decisions, so that
+// it is possible to find out why a client got less than it wanted.
+// Decisions are sampled, except for verbose resources whose every
+// decision is logged along with the resource's totals.
+type DecisionLogger struct {
+	mu         sync.Mutex
+	enc        *json.Encoder
+	sampleRate float64
+	verbose    []string
+	random     func() float64
+}
+
+// NewDecisionLogger returns a logger writing to w a fraction
+// sampleRate of the decisions, and all the decisions about resources
+// matching one of the verbose globs.
+func NewDecisionLogger(w io.Writer, sampleRate float64, verbose []string) *DecisionLogger {
+	return &DecisionLogger{
+		enc:        json.NewEncoder(w),
+		sampleRate: sampleRate,
+		verbose:    verbose,
+		random:     rand.Float64,
+	}
+}
+
+// WithDecisionLogger makes the server log its capacity decisions to l.
+func WithDecisionLogger(l *DecisionLogger) ServerOption {
+	return func(server *Server) {
+		server.decisions = l
+	}
+}
+
+// decisionRecord is a line of the decision log. The totals are only
+// set for verbose resources.
+type decisionRecord struct {
+	Time         time.Time `json:"time"`
+	ClientId     string    `json:"client_id"`
+	ResourceId   string    `json:"resource_id"`
+	Has          int32     `json:"has"`
+	Want         int32     `json:"want"`
+	Granted      int32     `json:"granted"`
+	Algorithm    string    `json:"algorithm"`
+	LearningMode bool      `json:"learning_mode"`
+	Capacity     *int      `json:"capacity,omitempty"`
+	SumHas       *int32    `json:"sum_has,omitempty"`
+	SumWant      *int32    `json:"sum_want,omitempty"`
+	Clients      *int32    `json:"clients,omitempty"`
+}
+
+func (l *DecisionLogger) isVerbose(resourceId string) bool {
+	for _, glob := range l.verbose {
+		if matched, _ := filepath.Match(glob, resourceId); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// logDecision records the lease granted to request. res.mu must be
+// held. It does nothing on a nil logger.
+func (l *DecisionLogger) logDecision(res *Resource, request *Request, lease Lease, learning bool) {
+	if l == nil {
+		return
+	}
+	verbose := l.isVerbose(res.resourceId)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !verbose && l.random() >= l.sampleRate {
+		return
+	}
+
+	record := decisionRecord{
+		Time:         res.clock.Now(),
+		ClientId:     request.ClientId,
+		ResourceId:   res.resourceId,
+		Has:          request.Has,
+		Want:         request.Want,
+		Granted:      lease.Has,
+		Algorithm:    res.config.GetAlgo().GetKind().String(),
+		LearningMode: learning,
+	}
+	if verbose {
+		capacity, sumHas, sumWant, count := res.Capacity(), res.store.SumHas(), res.store.SumWant(), res.store.Count()
+		record.Capacity, record.SumHas, record.SumWant, record.Clients = &capacity, &sumHas, &sumWant, &count
+	}
+	if err := l.enc.Encode(record); err != nil {
+		log.Printf("cannot write decision log: %v", err)
+	}
+}
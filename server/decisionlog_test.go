@@ -0,0 +1,80 @@
+package doorman
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/notfresh/zxdoorman/proto"
+	"golang.org/x/net/context"
+)
+
+func TestDecisionLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewDecisionLogger(&buf, 0.5, []string{"verbose*"})
+	samples := []float64{0.7, 0.2}
+	logger.random = func() float64 {
+		r := samples[0]
+		samples = samples[1:]
+		return r
+	}
+
+	server, err := NewServer(context.Background(), "test", WithDecisionLogger(logger))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	if err := server.LoadConfig(context.Background(), &proto.ResourceRepository{
+		Resources: []*proto.ResourcePB{{
+			IdentifierGlob: "*",
+			Capacity:       100,
+			Algo:           &proto.AlgorithmPB{Kind: proto.AlgorithmPB_NO_ALGORITHM, LeaseLength: 60},
+		}},
+	}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	getCapacity(t, server, "dropped", "res", 10, 20)
+	getCapacity(t, server, "sampled", "res", 10, 20)
+	getCapacity(t, server, "client", "verbose1", 5, 5)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if want, got := 2, len(lines); want != got {
+		t.Fatalf("logged %v decisions want %v:\n%s", got, want, buf.String())
+	}
+
+	var sampled, verbose map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &sampled); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &verbose); err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]interface{}{
+		"client_id":     "sampled",
+		"resource_id":   "res",
+		"has":           10.0,
+		"want":          20.0,
+		"granted":       10.0,
+		"algorithm":     "NO_ALGORITHM",
+		"learning_mode": true,
+	} {
+		if got := sampled[key]; got != want {
+			t.Errorf("sampled[%q] = %v want %v", key, got, want)
+		}
+	}
+	if _, ok := sampled["capacity"]; ok {
+		t.Errorf("sampled decision has the resource totals: %v", lines[0])
+	}
+	for key, want := range map[string]interface{}{
+		"resource_id": "verbose1",
+		"capacity":    100.0,
+		"sum_has":     5.0,
+		"clients":     1.0,
+	} {
+		if got := verbose[key]; got != want {
+			t.Errorf("verbose[%q] = %v want %v", key, got, want)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package doorman
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/notfresh/zxdoorman/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	goproto "google.golang.org/protobuf/proto"
+)
+
+// defaultConfigHistory is the number of configurations a server
+// remembers if not told otherwise.
+const defaultConfigHistory = 10
+
+// configVersion is a configuration the server has loaded.
+type configVersion struct {
+	version    int64
+	loadedAt   time.Time
+	hash       string
+	config     *proto.ResourceRepository
+	rollbackOf int64
+}
+
+func (v *configVersion) toProto() *proto.ConfigVersion {
+	return &proto.ConfigVersion{
+		Version:    v.version,
+		LoadTime:   v.loadedAt.Unix(),
+		Hash:       v.hash,
+		Config:     v.config,
+		RollbackOf: v.rollbackOf,
+	}
+}
+
+// configHash returns the hex encoded SHA-256 of config.
+func configHash(config *proto.ResourceRepository) string {
+	data, err := goproto.MarshalOptions{Deterministic: true}.Marshal(config)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// WithConfigHistory sets the number of loaded configurations the
+// server keeps for rollbacks. It always keeps the current one.
+func WithConfigHistory(n int) ServerOption {
+	return func(server *Server) {
+		server.historySize = n
+	}
+}
+
+// recordConfig adds config to the history, dropping the oldest
+// versions beyond the history size. server.mu must be held.
+func (server *Server) recordConfig(config *proto.ResourceRepository, rollbackOf int64) *configVersion {
+	server.lastVersion++
+	v := &configVersion{
+		version:    server.lastVersion,
+		loadedAt:   server.configLoadedAt,
+		hash:       configHash(config),
+		config:     config,
+		rollbackOf: rollbackOf,
+	}
+	server.history = append(server.history, v)
+	n := server.historySize
+	if n < 1 {
+		n = 1
+	}
+	if len(server.history) > n {
+		server.history = append([]*configVersion(nil), server.history[len(server.history)-n:]...)
+	}
+	return v
+}
+
+// ConfigHistory returns the configurations the server remembers,
+// oldest first. The last one is the current configuration.
+func (server *Server) ConfigHistory() []*proto.ConfigVersion {
+	server.mu.RLock()
+	defer server.mu.RUnlock()
+	var versions []*proto.ConfigVersion
+	for _, v := range server.history {
+		versions = append(versions, v.toProto())
+	}
+	return versions
+}
+
+// Rollback loads again the configuration with the given version.
+// It is recorded in the history as a new version.
+func (server *Server) Rollback(ctx context.Context, version int64) (*proto.ConfigVersion, error) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	for _, v := range server.history {
+		if v.version == version {
+			current := server.loadConfig(v.config, server.expiryTimes, version)
+			server.metrics.configLoads.Inc()
+			return current.toProto(), nil
+		}
+	}
+	return nil, status.Errorf(codes.NotFound, "no configuration with version %v", version)
+}
+
+// GetConfigHistory returns the recently loaded configurations, or
+// only their part about a namespace. It is part of the
+// doorman.AdminServer implementation.
+func (server *Server) GetConfigHistory(ctx context.Context, in *proto.GetConfigHistoryRequest) (*proto.GetConfigHistoryResponse, error) {
+	if err := server.checkAdmin(ctx, in.GetNamespace()); err != nil {
+		return nil, err
+	}
+	versions := server.ConfigHistory()
+	if name := in.GetNamespace(); name != "" {
+		for _, v := range versions {
+			v.Config = namespaceConfig(v.Config, name)
+		}
+	}
+	return &proto.GetConfigHistoryResponse{Versions: versions}, nil
+}
+
+// RollbackConfig loads a previous configuration again. It is part of
+// the doorman.AdminServer implementation.
+func (server *Server) RollbackConfig(ctx context.Context, in *proto.RollbackConfigRequest) (*proto.RollbackConfigResponse, error) {
+	if err := server.checkAdmin(ctx, ""); err != nil {
+		return nil, err
+	}
+	current, err := server.Rollback(ctx, in.GetVersion())
+	if err != nil {
+		return nil, err
+	}
+	return &proto.RollbackConfigResponse{Current: current}, nil
+}
@@ -0,0 +1,121 @@
+package doorman
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/notfresh/zxdoorman/proto"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func repositoryWithCapacity(capacity int32) *proto.ResourceRepository {
+	return &proto.ResourceRepository{
+		Resources: []*proto.ResourcePB{
+			{
+				IdentifierGlob: "*",
+				Capacity:       capacity,
+				Algo:           &proto.AlgorithmPB{LeaseLength: 60},
+			},
+		},
+	}
+}
+
+func TestConfigHistory(t *testing.T) {
+	server, err := NewServer(context.Background(), "test", WithConfigHistory(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	for _, capacity := range []int32{10, 20, 30} {
+		if err := server.LoadConfig(context.Background(), repositoryWithCapacity(capacity), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out, err := server.GetConfigHistory(context.Background(), &proto.GetConfigHistoryRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	versions := out.GetVersions()
+	if want, got := 2, len(versions); want != got {
+		t.Fatalf("len(versions) = %v want %v", got, want)
+	}
+	for i, want := range []struct {
+		version  int64
+		capacity int32
+	}{{2, 20}, {3, 30}} {
+		v := versions[i]
+		if v.GetVersion() != want.version || v.GetConfig().GetResources()[0].GetCapacity() != want.capacity {
+			t.Errorf("versions[%v] = %v want version %v with capacity %v", i, v, want.version, want.capacity)
+		}
+		if v.GetHash() == "" || v.GetLoadTime() == 0 {
+			t.Errorf("versions[%v] = %v, want hash and load time", i, v)
+		}
+	}
+	if versions[0].GetHash() == versions[1].GetHash() {
+		t.Errorf("different configurations have the same hash %v", versions[0].GetHash())
+	}
+}
+
+func TestConfigHistoryKeepsCurrent(t *testing.T) {
+	for _, size := range []int{0, -1} {
+		server, err := NewServer(context.Background(), "test", WithConfigHistory(size))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, capacity := range []int32{10, 20, 30} {
+			if err := server.LoadConfig(context.Background(), repositoryWithCapacity(capacity), nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+		history := server.ConfigHistory()
+		if len(history) != 1 || history[0].GetVersion() != 3 {
+			t.Errorf("history of size %v: %v, want only the current version 3", size, history)
+		}
+		server.Close()
+	}
+}
+
+func TestRollbackConfig(t *testing.T) {
+	server, err := MakeTestServer(repositoryWithCapacity(100).Resources...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	res := server.getOrCreateResource("res")
+	if err := server.LoadConfig(context.Background(), repositoryWithCapacity(5), nil); err != nil {
+		t.Fatal(err)
+	}
+	if want, got := 5, res.Capacity(); want != got {
+		t.Fatalf("Capacity() = %v want %v", got, want)
+	}
+
+	out, err := server.RollbackConfig(context.Background(), &proto.RollbackConfigRequest{Version: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := 100, res.Capacity(); want != got {
+		t.Errorf("after rollback Capacity() = %v want %v", got, want)
+	}
+	current := out.GetCurrent()
+	if current.GetVersion() != 3 || current.GetRollbackOf() != 1 {
+		t.Errorf("current = %v want version 3 rolling back version 1", current)
+	}
+	history := server.ConfigHistory()
+	if history[0].GetHash() != current.GetHash() {
+		t.Errorf("rolled back hash %v want %v", current.GetHash(), history[0].GetHash())
+	}
+
+	if metrics := scrape(t, server); !strings.Contains(metrics, "doorman_config_loads_total 3") {
+		t.Errorf("the rollback is not counted as a config load:\n%v", metrics)
+	}
+
+	_, err = server.RollbackConfig(context.Background(), &proto.RollbackConfigRequest{Version: 42})
+	if want, got := codes.NotFound, status.Code(err); want != got {
+		t.Errorf("rollback to unknown version: code %v want %v", got, want)
+	}
+}
@@ -0,0 +1,129 @@
+package doorman
+
+import (
+	"context"
+	"strings"
+
+	"github.com/notfresh/zxdoorman/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Identifier returns the identity of the peer that sent the request
+// in ctx.
+type Identifier func(ctx context.Context) (string, error)
+
+// CertificateIdentity is an Identifier returning the common name of
+// the verified client certificate of the peer. The server must verify
+// client certificates for it to work.
+func CertificateIdentity(ctx context.Context) (string, error) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return "", status.Error(codes.Unauthenticated, "no peer")
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.VerifiedChains) == 0 || len(info.State.VerifiedChains[0]) == 0 {
+		return "", status.Error(codes.Unauthenticated, "no verified client certificate")
+	}
+	name := info.State.VerifiedChains[0][0].Subject.CommonName
+	if name == "" {
+		return "", status.Error(codes.Unauthenticated, "the client certificate has no common name")
+	}
+	return name, nil
+}
+
+// TokenIdentity returns an Identifier looking up the bearer token in
+// the authorization metadata of the request in tokens, which maps
+// tokens to identities.
+func TokenIdentity(tokens map[string]string) Identifier {
+	return func(ctx context.Context) (string, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		for _, value := range md.Get("authorization") {
+			token := strings.TrimPrefix(value, "Bearer ")
+			if id, ok := tokens[token]; ok && token != value {
+				return id, nil
+			}
+		}
+		return "", status.Error(codes.Unauthenticated, "no valid token")
+	}
+}
+
+type identityKey struct{}
+
+// IdentityFromContext returns the identity the identity interceptors
+// stored in ctx, if any.
+func IdentityFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(identityKey{}).(string)
+	return id, ok
+}
+
+// IdentityInterceptors return the interceptors binding the client
+// ids of requests to the identity of the peer, as told by identify.
+// Requests whose client id is not that identity are rejected with
+// PERMISSION_DENIED, and requests whose peer cannot be identified
+// with UNAUTHENTICATED. Requests with no client id get the identity
+// as client id.
+func IdentityInterceptors(identify Identifier) (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor) {
+	unary := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		id, err := identify(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if err := bindClientID(req, id); err != nil {
+			return nil, err
+		}
+		return handler(context.WithValue(ctx, identityKey{}, id), req)
+	}
+	stream := func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		id, err := identify(ss.Context())
+		if err != nil {
+			return err
+		}
+		return handler(srv, &identifiedStream{
+			ServerStream: ss,
+			ctx:          context.WithValue(ss.Context(), identityKey{}, id),
+			id:           id,
+		})
+	}
+	return unary, stream
+}
+
+// identifiedStream binds the client ids of the messages received on
+// a stream to the identity of its peer.
+type identifiedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+	id  string
+}
+
+func (s *identifiedStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *identifiedStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	return bindClientID(m, s.id)
+}
+
+// bindClientID sets the client id of req to id if it is empty, and
+// returns an error if it is another one.
+func bindClientID(req interface{}, id string) error {
+	in, ok := req.(*proto.GetCapacityRequest)
+	if !ok {
+		return nil
+	}
+	switch in.ClientId {
+	case "":
+		in.ClientId = id
+	case id:
+	default:
+		return status.Errorf(codes.PermissionDenied, "client %q cannot request capacity as %q", id, in.ClientId)
+	}
+	return nil
+}
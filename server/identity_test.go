@@ -0,0 +1,77 @@
+package doorman
+
+import (
+	"testing"
+	"time"
+
+	"github.com/notfresh/zxdoorman/proto"
+	"golang.org/x/net/context"
+	rpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestTokenIdentity(t *testing.T) {
+	server, err := MakeTestServerWithClock(NewManualClock(time.Unix(1000000, 0)), &proto.ResourcePB{
+		IdentifierGlob: "*",
+		Capacity:       100,
+		Algo:           &proto.AlgorithmPB{LeaseLength: 60, RefreshInterval: 10},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	unary, stream := IdentityInterceptors(TokenIdentity(map[string]string{"secret": "alice"}))
+	client, stop := serve(t, server, []rpc.ServerOption{rpc.UnaryInterceptor(unary), rpc.StreamInterceptor(stream)})
+	defer stop()
+
+	request := func(clientID string) *proto.GetCapacityRequest {
+		return &proto.GetCapacityRequest{
+			ClientId: clientID,
+			Resource: []*proto.GetCapacityRequest_ResourceRequest{{ResourceId: "res", Want: 10}},
+		}
+	}
+	withToken := func(token string) (context.Context, context.CancelFunc) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if token != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+		}
+		return ctx, cancel
+	}
+
+	for _, c := range []struct {
+		token, clientID string
+		code            codes.Code
+	}{
+		{"secret", "alice", codes.OK},
+		{"secret", "", codes.OK},
+		{"secret", "bob", codes.PermissionDenied},
+		{"guess", "alice", codes.Unauthenticated},
+		{"", "alice", codes.Unauthenticated},
+	} {
+		ctx, cancel := withToken(c.token)
+		_, err := client.GetCapacity(ctx, request(c.clientID))
+		cancel()
+		if got := status.Code(err); got != c.code {
+			t.Errorf("token %q, client id %q: code %v want %v", c.token, c.clientID, got, c.code)
+		}
+	}
+
+	// The request without a client id got the lease of alice.
+	leases := server.getOrCreateResource("res").Status().Leases
+	if len(leases) != 1 || leases[0].ClientId != "alice" {
+		t.Errorf("leases = %+v, want one for alice", leases)
+	}
+
+	ctx, cancel := withToken("secret")
+	defer cancel()
+	watch, err := client.WatchCapacity(ctx, request("bob"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := watch.Recv(); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("watching as another client: %v, want PermissionDenied", err)
+	}
+}
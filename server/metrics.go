@@ -0,0 +1,185 @@
+package doorman
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"google.golang.org/grpc/status"
+)
+
+// serverMetrics are the Prometheus metrics of a server. Every server
+// has its own registry, served on /metrics by its debug handler.
+type serverMetrics struct {
+	registry         *prometheus.Registry
+	requests         *prometheus.CounterVec
+	requestDuration  *prometheus.HistogramVec
+	configLoads      prometheus.Counter
+	configFailures   prometheus.Counter
+	resourcesNew     prometheus.Counter
+	resourcesGone    prometheus.Counter
+	learningRestarts prometheus.Counter
+}
+
+func newServerMetrics(server *Server) *serverMetrics {
+	m := &serverMetrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "doorman",
+			Name:      "requests_total",
+			Help:      "Number of RPCs handled, by method and status code.",
+		}, []string{"method", "code"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "doorman",
+			Name:      "request_duration_seconds",
+			Help:      "Latency of the RPCs handled, by method.",
+			Buckets:   prometheus.ExponentialBuckets(0.0001, 4, 10),
+		}, []string{"method"}),
+		configLoads: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "doorman",
+			Name:      "config_loads_total",
+			Help:      "Number of configurations loaded.",
+		}),
+		configFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "doorman",
+			Name:      "config_load_failures_total",
+			Help:      "Number of configurations that could not be loaded.",
+		}),
+		resourcesNew: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "doorman",
+			Name:      "resources_created_total",
+			Help:      "Number of resources created on first request.",
+		}),
+		resourcesGone: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "doorman",
+			Name:      "resources_evicted_total",
+			Help:      "Number of idle resources evicted.",
+		}),
+		learningRestarts: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "doorman",
+			Name:      "learning_mode_restarts_total",
+			Help:      "Number of times learning mode restarted for every resource because the server became the master.",
+		}),
+	}
+	m.registry.MustRegister(
+		m.requests,
+		m.requestDuration,
+		m.configLoads,
+		m.configFailures,
+		m.resourcesNew,
+		m.resourcesGone,
+		m.learningRestarts,
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: "doorman",
+			Name:      "master",
+			Help:      "1 if the server is the master, 0 otherwise.",
+		}, func() float64 {
+			if server.IsMaster() {
+				return 1
+			}
+			return 0
+		}),
+		resourceCollector{server},
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// observeRequest records an RPC to method that started at start and
+// returned err.
+func (m *serverMetrics) observeRequest(method string, start time.Time, err error) {
+	m.requests.WithLabelValues(method, status.Code(err).String()).Inc()
+	m.requestDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+}
+
+var (
+	capacityDesc = prometheus.NewDesc(
+		"doorman_resource_capacity",
+		"Capacity of the resource.",
+		[]string{"resource"}, nil)
+	sumHasDesc = prometheus.NewDesc(
+		"doorman_resource_sum_has",
+		"Sum of the capacity leased to the clients of the resource.",
+		[]string{"resource"}, nil)
+	sumWantDesc = prometheus.NewDesc(
+		"doorman_resource_sum_want",
+		"Sum of the capacity wanted by the clients of the resource.",
+		[]string{"resource"}, nil)
+	clientsDesc = prometheus.NewDesc(
+		"doorman_resource_clients",
+		"Number of clients holding a lease on the resource.",
+		[]string{"resource"}, nil)
+	learningModeDesc = prometheus.NewDesc(
+		"doorman_resource_learning_mode",
+		"1 if the resource is in learning mode, 0 otherwise.",
+		[]string{"resource"}, nil)
+)
+
+// resourceCollector exports the state of the server's resources at
+// scrape time.
+type resourceCollector struct {
+	server *Server
+}
+
+func (c resourceCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- capacityDesc
+	ch <- sumHasDesc
+	ch <- sumWantDesc
+	ch <- clientsDesc
+	ch <- learningModeDesc
+}
+
+// Collect reads the resources without cleaning them or checking their
+// capacity, so that scraping never changes the state of the server.
+func (c resourceCollector) Collect(ch chan<- prometheus.Metric) {
+	c.server.mu.RLock()
+	resources := make([]*Resource, 0, len(c.server.resources))
+	for _, res := range c.server.resources {
+		resources = append(resources, res)
+	}
+	c.server.mu.RUnlock()
+
+	for _, res := range resources {
+		m := res.metrics()
+		learning := 0.0
+		if m.learning {
+			learning = 1
+		}
+		ch <- prometheus.MustNewConstMetric(capacityDesc, prometheus.GaugeValue, float64(m.capacity), m.id)
+		ch <- prometheus.MustNewConstMetric(sumHasDesc, prometheus.GaugeValue, float64(m.sumHas), m.id)
+		ch <- prometheus.MustNewConstMetric(sumWantDesc, prometheus.GaugeValue, float64(m.sumWant), m.id)
+		ch <- prometheus.MustNewConstMetric(clientsDesc, prometheus.GaugeValue, float64(m.count), m.id)
+		ch <- prometheus.MustNewConstMetric(learningModeDesc, prometheus.GaugeValue, learning, m.id)
+	}
+}
+
+// resourceMetrics is what the metrics export about a resource.
+type resourceMetrics struct {
+	id                     string
+	capacity               int
+	sumHas, sumWant, count int32
+	learning               bool
+}
+
+// metrics returns the metrics of the resource. Leases that expired but
+// were not cleaned yet are left out rather than released.
+func (res *Resource) metrics() resourceMetrics {
+	res.mu.RLock()
+	defer res.mu.RUnlock()
+	m := resourceMetrics{
+		id:       res.resourceId,
+		capacity: res.Capacity(),
+		learning: res.inLearningMode(),
+	}
+	now := res.clock.Now()
+	for _, lease := range res.store.Map() {
+		if now.After(lease.ExpireTime) {
+			continue
+		}
+		m.sumHas += lease.Has
+		m.sumWant += lease.Want
+		m.count++
+	}
+	return m
+}
@@ -0,0 +1,101 @@
+package doorman
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/notfresh/zxdoorman/proto"
+)
+
+func scrape(t *testing.T, server *Server) string {
+	t.Helper()
+	ts := httptest.NewServer(server.DebugHandler())
+	defer ts.Close()
+	resp, err := ts.Client().Get(ts.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestMetrics(t *testing.T) {
+	clock := NewManualClock(time.Unix(1000000, 0))
+	server, err := MakeTestServerWithClock(clock, &proto.ResourcePB{
+		IdentifierGlob: "*",
+		Capacity:       100,
+		Algo:           &proto.AlgorithmPB{LeaseLength: 60},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	getCapacity(t, server, "client1", "res1", 10, 20)
+	getCapacity(t, server, "client2", "res1", 5, 5)
+	server.getOrCreateResource("idle")
+	server.ConfigLoadFailed()
+	server.evictIdleResources(clock.Now().Add(defaultResourceIdleTime))
+
+	metrics := scrape(t, server)
+	for _, want := range []string{
+		`doorman_resource_capacity{resource="res1"} 100`,
+		`doorman_resource_sum_has{resource="res1"} 15`,
+		`doorman_resource_clients{resource="res1"} 2`,
+		`doorman_resource_learning_mode{resource="res1"} 1`,
+		`doorman_requests_total{code="OK",method="GetCapacity"} 2`,
+		`doorman_request_duration_seconds_count{method="GetCapacity"} 2`,
+		`doorman_config_loads_total 1`,
+		`doorman_config_load_failures_total 1`,
+		`doorman_resources_created_total 2`,
+		`doorman_resources_evicted_total 1`,
+	} {
+		if !strings.Contains(metrics, want) {
+			t.Errorf("metrics do not contain %q", want)
+		}
+	}
+	if strings.Contains(metrics, `resource="idle"`) {
+		t.Errorf("metrics contain the evicted resource")
+	}
+}
+
+func TestMetricsReadOnly(t *testing.T) {
+	clock := NewManualClock(time.Unix(1000000, 0))
+	server, err := MakeTestServerWithClock(clock, &proto.ResourcePB{
+		IdentifierGlob: "*",
+		Capacity:       100,
+		Algo:           &proto.AlgorithmPB{LeaseLength: 60},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	getCapacity(t, server, "client1", "res1", 10, 20)
+	// Stops the run loop, which would clean the resource itself.
+	server.Close()
+
+	clock.Advance(2 * time.Minute)
+	metrics := scrape(t, server)
+	for _, want := range []string{
+		`doorman_resource_sum_has{resource="res1"} 0`,
+		`doorman_resource_clients{resource="res1"} 0`,
+	} {
+		if !strings.Contains(metrics, want) {
+			t.Errorf("metrics do not contain %q", want)
+		}
+	}
+	// The expired lease is left for the resource to clean.
+	res := server.getOrCreateResource("res1")
+	res.mu.RLock()
+	count := res.store.Count()
+	res.mu.RUnlock()
+	if count != 1 {
+		t.Errorf("%v leases left after scraping, want 1", count)
+	}
+}
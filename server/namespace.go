@@ -0,0 +1,234 @@
+package doorman
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/notfresh/zxdoorman/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// namespaceSeparator separates the name of a namespace from the id of
+// the resource in qualified resource ids.
+const namespaceSeparator = ":"
+
+// namespace keeps the resources of a namespace from giving out more
+// than its capacity in total. Like borrowing groups, it records what
+// its resources have rather than reading it from them, so that they
+// never need each other's locks.
+type namespace struct {
+	name string
+	mu   sync.Mutex
+	// capacity is the total capacity of the resources, 0 for no
+	// limit.
+	capacity int32
+	has      map[string]int32
+}
+
+func newNamespace(name string) *namespace {
+	return &namespace{name: name, has: make(map[string]int32)}
+}
+
+// free returns what the resource id can have without the resources of
+// the namespace having more than its capacity, and false if there is
+// no limit. ns.mu must be held.
+func (ns *namespace) free(id string) (int, bool) {
+	if ns.capacity <= 0 {
+		return 0, false
+	}
+	free := ns.capacity
+	for other, has := range ns.has {
+		if other != id {
+			free -= has
+		}
+	}
+	if free < 0 {
+		return 0, true
+	}
+	return int(free), true
+}
+
+// status returns a snapshot of the state of the namespace.
+func (ns *namespace) status() NamespaceStatus {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	status := NamespaceStatus{Name: ns.name, Capacity: ns.capacity}
+	for _, has := range ns.has {
+		status.Has += has
+	}
+	return status
+}
+
+// setCapacity changes the total capacity of the namespace.
+func (ns *namespace) setCapacity(capacity int32) {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	ns.capacity = capacity
+}
+
+// record records what the resource id has. ns.mu must be held.
+func (ns *namespace) record(id string, has int32) {
+	ns.has[id] = has
+}
+
+// update does the work of record, taking ns.mu.
+func (ns *namespace) update(id string, has int32) {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	ns.record(id, has)
+}
+
+// remove forgets the resource id.
+func (ns *namespace) remove(id string) {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	delete(ns.has, id)
+}
+
+// splitNamespace splits a resource id qualified by a namespace into
+// the name of the namespace and the id of the resource within it. ok
+// is false if id is not qualified.
+func splitNamespace(id string) (name, local string, ok bool) {
+	i := strings.Index(id, namespaceSeparator)
+	if i < 0 {
+		return "", id, false
+	}
+	return id[:i], id[i+len(namespaceSeparator):], true
+}
+
+// qualify returns the id of the resource local in the namespace name,
+// or local itself if name is empty.
+func qualify(name, local string) string {
+	if name == "" || local == "" {
+		return local
+	}
+	return name + namespaceSeparator + local
+}
+
+// findNamespace returns the configuration of the namespace name, nil
+// if there is none.
+func findNamespace(config *proto.ResourceRepository, name string) *proto.NamespacePB {
+	for _, ns := range config.GetNamespaces() {
+		if ns.GetName() == name {
+			return ns
+		}
+	}
+	return nil
+}
+
+// namespaceOf returns the name of the configured namespace the
+// resource id is in, "" if it is in none. server.mu must be held.
+func (server *Server) namespaceOf(id string) string {
+	name, _, ok := splitNamespace(id)
+	if !ok || findNamespace(server.config, name) == nil {
+		return ""
+	}
+	return name
+}
+
+// loadNamespaces updates the namespaces of the server to those of
+// config. The usage recorded by the namespaces that stay is kept.
+// server.mu must be held.
+func (server *Server) loadNamespaces(config *proto.ResourceRepository) {
+	namespaces := make(map[string]*namespace)
+	for _, cfg := range config.GetNamespaces() {
+		ns, ok := server.namespaces[cfg.GetName()]
+		if !ok {
+			ns = newNamespace(cfg.GetName())
+		}
+		ns.setCapacity(cfg.GetCapacity())
+		namespaces[cfg.GetName()] = ns
+	}
+	server.namespaces = namespaces
+}
+
+// setNamespace makes the resource one of the namespace ns, or of none
+// if it is nil.
+func (res *Resource) setNamespace(ns *namespace) {
+	res.mu.Lock()
+	defer res.mu.Unlock()
+	if res.namespace == ns {
+		return
+	}
+	if res.namespace != nil {
+		res.namespace.remove(res.resourceId)
+	}
+	res.namespace = ns
+	res.reportUsage()
+}
+
+// namespaceName returns the name of the namespace of the resource, ""
+// if it has none. res.mu must be held.
+func (res *Resource) namespaceName() string {
+	if res.namespace == nil {
+		return ""
+	}
+	return res.namespace.name
+}
+
+// quota returns the namespace whose capacity limits what the resource
+// gives out, nil if there is none. Children count in their parent's
+// usage, not in their own. res.mu must be held.
+func (res *Resource) quota() *namespace {
+	if res.parent != nil {
+		return nil
+	}
+	return res.namespace
+}
+
+// checkAdmin returns a PERMISSION_DENIED error if the identity of the
+// caller in ctx may not use the admin service for the namespace name,
+// or for the whole repository if name is empty. Without administrators
+// anywhere in the configuration everyone may; with some, callers
+// without an identity may not.
+func (server *Server) checkAdmin(ctx context.Context, name string) error {
+	server.mu.RLock()
+	defer server.mu.RUnlock()
+	admins := server.config.GetAdministrators()
+	if name != "" {
+		ns := findNamespace(server.config, name)
+		if ns == nil {
+			return status.Errorf(codes.NotFound, "no namespace %q", name)
+		}
+		admins = append(admins[:len(admins):len(admins)], ns.GetAdministrators()...)
+	}
+	if !hasAdministrators(server.config) {
+		return nil
+	}
+	id, ok := IdentityFromContext(ctx)
+	if !ok {
+		return status.Errorf(codes.PermissionDenied, "administrators are configured but the caller has no identity")
+	}
+	if matchAny(admins, id) {
+		return nil
+	}
+	if name == "" {
+		return status.Errorf(codes.PermissionDenied, "%q is not an administrator", id)
+	}
+	return status.Errorf(codes.PermissionDenied, "%q is not an administrator of namespace %q", id, name)
+}
+
+// hasAdministrators returns true if config restricts the admin service
+// to administrators, of the repository or of a namespace.
+func hasAdministrators(config *proto.ResourceRepository) bool {
+	if len(config.GetAdministrators()) > 0 {
+		return true
+	}
+	for _, ns := range config.GetNamespaces() {
+		if len(ns.GetAdministrators()) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// namespaceConfig returns the part of config about the namespace name.
+func namespaceConfig(config *proto.ResourceRepository, name string) *proto.ResourceRepository {
+	restricted := &proto.ResourceRepository{}
+	if ns := findNamespace(config, name); ns != nil {
+		restricted.Namespaces = []*proto.NamespacePB{ns}
+	}
+	return restricted
+}
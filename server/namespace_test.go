@@ -0,0 +1,201 @@
+package doorman
+
+import (
+	"testing"
+	"time"
+
+	"github.com/notfresh/zxdoorman/proto"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// teams is a configuration with the namespaces search and ads.
+var teams = &proto.ResourceRepository{
+	Resources: []*proto.ResourcePB{
+		{IdentifierGlob: "*", Capacity: 1000, Algo: &proto.AlgorithmPB{LeaseLength: 60}},
+	},
+	Namespaces: []*proto.NamespacePB{
+		{
+			Name:     "search",
+			Capacity: 100,
+			Resources: []*proto.ResourcePB{
+				{IdentifierGlob: "index/*", Capacity: 80, Algo: &proto.AlgorithmPB{LeaseLength: 60}},
+				{IdentifierGlob: "pool", Capacity: 50, Algo: &proto.AlgorithmPB{LeaseLength: 60}},
+				{IdentifierGlob: "crawl", Capacity: 50, Algo: &proto.AlgorithmPB{LeaseLength: 60}, Parent: "pool"},
+			},
+			Administrators: []string{"search-*"},
+		},
+		{
+			Name: "ads",
+			Resources: []*proto.ResourcePB{
+				{IdentifierGlob: "index/*", Capacity: 10, Algo: &proto.AlgorithmPB{LeaseLength: 60}},
+			},
+		},
+	},
+	Administrators: []string{"root"},
+}
+
+func TestNamespaces(t *testing.T) {
+	clock := NewManualClock(time.Unix(1000000, 0))
+	server, err := MakeTestServerWithClock(clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	if err := server.LoadConfig(context.Background(), teams, nil); err != nil {
+		t.Fatal(err)
+	}
+	// Gets out of learning mode.
+	clock.Advance(2 * time.Minute)
+
+	for _, c := range []struct {
+		client, resource string
+		wants, gets      int32
+	}{
+		// Each namespace has its own resources.
+		{"x", "search:index/a", 70, 70},
+		{"x", "ads:index/a", 70, 10},
+		// Unknown namespaces are not namespaces.
+		{"x", "video:index", 70, 70},
+		// The resources of search give out 100 in total.
+		{"y", "search:index/b", 70, 30},
+		// Children count through their parent, in the namespace.
+		{"z", "search:crawl", 10, 0},
+	} {
+		got := getCapacity(t, server, c.client, c.resource, 0, c.wants).GetGets().GetCapacity()
+		if got != c.gets {
+			t.Errorf("%v wanting %v of %v got %v, want %v", c.client, c.wants, c.resource, got, c.gets)
+		}
+	}
+	if parent := server.getOrCreateResource("search:crawl").Status().Parent; parent != "search:pool" {
+		t.Errorf("parent of search:crawl is %q, want search:pool", parent)
+	}
+
+	// x releasing search:index/a frees capacity for the others.
+	getCapacity(t, server, "x", "search:index/a", 70, 0)
+	if got := getCapacity(t, server, "z", "search:crawl", 0, 10).GetGets().GetCapacity(); got != 10 {
+		t.Errorf("z got %v, want 10", got)
+	}
+
+	status := server.Status().InNamespace("search")
+	if len(status.Namespaces) != 1 || status.Namespaces[0].Has != 40 {
+		t.Errorf("namespaces = %+v, want search having 40", status.Namespaces)
+	}
+	for _, res := range status.Resources {
+		if res.Namespace != "search" {
+			t.Errorf("resource %v of namespace %q in the status of search", res.ID, res.Namespace)
+		}
+	}
+}
+
+func TestNamespaceAdministrators(t *testing.T) {
+	server, err := MakeTestServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	if err := server.LoadConfig(context.Background(), teams, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		identity, namespace string
+		code                codes.Code
+	}{
+		// Without an identity, nobody is an administrator.
+		{"", "", codes.PermissionDenied},
+		{"", "search", codes.PermissionDenied},
+		{"root", "", codes.OK},
+		{"root", "search", codes.OK},
+		{"search-oncall", "search", codes.OK},
+		{"search-oncall", "", codes.PermissionDenied},
+		{"search-oncall", "ads", codes.PermissionDenied},
+		{"search-oncall", "video", codes.NotFound},
+	} {
+		ctx := context.Background()
+		if c.identity != "" {
+			ctx = context.WithValue(ctx, identityKey{}, c.identity)
+		}
+		out, err := server.GetConfigHistory(ctx, &proto.GetConfigHistoryRequest{Namespace: c.namespace})
+		if code := status.Code(err); code != c.code {
+			t.Errorf("%q reading the history of %q: %v, want %v", c.identity, c.namespace, err, c.code)
+			continue
+		}
+		if err != nil || c.namespace == "" {
+			continue
+		}
+		config := out.Versions[len(out.Versions)-1].Config
+		if len(config.Resources) != 0 || len(config.Namespaces) != 1 || config.Namespaces[0].Name != c.namespace {
+			t.Errorf("history of %q: %v", c.namespace, config)
+		}
+	}
+
+	version := server.ConfigHistory()[0].GetVersion()
+	_, err = server.RollbackConfig(context.Background(), &proto.RollbackConfigRequest{Version: version})
+	if code := status.Code(err); code != codes.PermissionDenied {
+		t.Errorf("rolling back without an identity: %v, want %v", err, codes.PermissionDenied)
+	}
+}
+
+func TestNamespaceAdministratorsOnly(t *testing.T) {
+	server, err := MakeTestServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	config := &proto.ResourceRepository{Namespaces: []*proto.NamespacePB{
+		{Name: "search", Administrators: []string{"search-*"}},
+		{Name: "ads"},
+	}}
+	if err := server.LoadConfig(context.Background(), config, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// Once a namespace has administrators, the repository is not open
+	// to all anymore.
+	for _, c := range []struct {
+		identity, namespace string
+		code                codes.Code
+	}{
+		{"search-oncall", "search", codes.OK},
+		{"search-oncall", "", codes.PermissionDenied},
+		{"someone", "", codes.PermissionDenied},
+		{"someone", "ads", codes.PermissionDenied},
+	} {
+		ctx := context.WithValue(context.Background(), identityKey{}, c.identity)
+		_, err := server.GetConfigHistory(ctx, &proto.GetConfigHistoryRequest{Namespace: c.namespace})
+		if code := status.Code(err); code != c.code {
+			t.Errorf("%q reading the history of %q: %v, want %v", c.identity, c.namespace, err, c.code)
+		}
+	}
+
+	version := server.ConfigHistory()[0].GetVersion()
+	ctx := context.WithValue(context.Background(), identityKey{}, "search-oncall")
+	_, err = server.RollbackConfig(ctx, &proto.RollbackConfigRequest{Version: version})
+	if code := status.Code(err); code != codes.PermissionDenied {
+		t.Errorf("a namespace administrator rolling back: %v, want %v", err, codes.PermissionDenied)
+	}
+}
+
+func TestValidateNamespaces(t *testing.T) {
+	server, err := MakeTestServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	for _, namespaces := range [][]*proto.NamespacePB{
+		{{Name: ""}},
+		{{Name: "a:b"}},
+		{{Name: "a"}, {Name: "a"}},
+		{{Name: "a", Capacity: -1}},
+		{{Name: "a", Administrators: []string{"["}}},
+		{{Name: "a", Resources: []*proto.ResourcePB{{IdentifierGlob: "["}}}},
+	} {
+		err := server.LoadConfig(context.Background(), &proto.ResourceRepository{Namespaces: namespaces}, nil)
+		if err == nil {
+			t.Errorf("namespaces %v: no error", namespaces)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package doorman
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/notfresh/zxdoorman/proto"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// RecordedRequest is a line of a recording: a GetCapacityRequest
+// and the time the server received it. The request is in the
+// protobuf JSON format.
+type RecordedRequest struct {
+	Time    time.Time       `json:"time"`
+	Request json.RawMessage `json:"request"`
+}
+
+// Recorder writes the GetCapacityRequests a server receives as JSON
+// lines, to be replayed later by doorman-replay.
+type Recorder struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewRecorder returns a Recorder writing to w.
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{enc: json.NewEncoder(w)}
+}
+
+// WithRecorder makes the server record the requests it receives.
+func WithRecorder(r *Recorder) ServerOption {
+	return func(server *Server) {
+		server.recorder = r
+	}
+}
+
+// Record writes in, received at when. It does nothing on a nil
+// Recorder.
+func (r *Recorder) Record(when time.Time, in *proto.GetCapacityRequest) error {
+	if r == nil {
+		return nil
+	}
+	data, err := protojson.Marshal(in)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.enc.Encode(RecordedRequest{Time: when, Request: data})
+}
+
+// ReadRecording calls fn for every request recorded in r, in order.
+// It stops at the first error.
+func ReadRecording(r io.Reader, fn func(when time.Time, in *proto.GetCapacityRequest) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var rec RecordedRequest
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return fmt.Errorf("line %d: %v", line, err)
+		}
+		in := new(proto.GetCapacityRequest)
+		if err := protojson.Unmarshal(rec.Request, in); err != nil {
+			return fmt.Errorf("line %d: %v", line, err)
+		}
+		if err := fn(rec.Time, in); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
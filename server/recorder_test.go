@@ -0,0 +1,85 @@
+package doorman
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/notfresh/zxdoorman/proto"
+	"golang.org/x/net/context"
+	goproto "google.golang.org/protobuf/proto"
+)
+
+func TestRecorder(t *testing.T) {
+	start := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewManualClock(start)
+	var buf bytes.Buffer
+	server, err := NewServer(context.Background(), "test", WithClock(clock), WithRecorder(NewRecorder(&buf)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	if err := server.LoadConfig(context.Background(), repositoryWithCapacity(100), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	getCapacity(t, server, "client1", "res", 0, 10)
+	clock.Advance(5 * time.Second)
+	getCapacity(t, server, "client2", "res", 3, 20)
+
+	var got []time.Time
+	var requests []*proto.GetCapacityRequest
+	if err := ReadRecording(&buf, func(when time.Time, in *proto.GetCapacityRequest) error {
+		got = append(got, when)
+		requests = append(requests, in)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []time.Time{start, start.Add(5 * time.Second)}; len(got) != 2 || !got[0].Equal(want[0]) || !got[1].Equal(want[1]) {
+		t.Fatalf("recorded times %v want %v", got, want)
+	}
+	want := &proto.GetCapacityRequest{
+		ClientId: "client2",
+		Resource: []*proto.GetCapacityRequest_ResourceRequest{
+			{ResourceId: "res", Has: &proto.Lease{Capacity: 3}, Want: 20},
+		},
+	}
+	if !goproto.Equal(requests[1], want) {
+		t.Errorf("recorded request %v want %v", requests[1], want)
+	}
+}
+
+func TestRecorderSkipsDenied(t *testing.T) {
+	var buf bytes.Buffer
+	server, err := NewServer(context.Background(), "test", WithRecorder(NewRecorder(&buf)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	if err := server.LoadConfig(context.Background(), &proto.ResourceRepository{
+		Resources: []*proto.ResourcePB{
+			{IdentifierGlob: "*", Capacity: 100, Algo: &proto.AlgorithmPB{LeaseLength: 60}, AllowedClients: []string{"good"}},
+		},
+	}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, client := range []string{"bad", "good"} {
+		server.GetCapacity(context.Background(), &proto.GetCapacityRequest{
+			ClientId: client,
+			Resource: []*proto.GetCapacityRequest_ResourceRequest{{ResourceId: "res", Want: 10}},
+		})
+	}
+	var clients []string
+	if err := ReadRecording(&buf, func(when time.Time, in *proto.GetCapacityRequest) error {
+		clients = append(clients, in.GetClientId())
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(clients) != 1 || clients[0] != "good" {
+		t.Errorf("recorded the requests of %v, want only good", clients)
+	}
+}
@@ -3,6 +3,7 @@ package doorman
 import (
 	goproto "github.com/golang/protobuf/proto"
 	"github.com/notfresh/zxdoorman/proto"
+	"log"
 	"sync"
 	"time"
 )
@@ -14,43 +15,406 @@ type Resource struct {
 	algo          Algorithm
 	learnerAlgo   Algorithm
 	learningEndAt time.Time
+	// learningSince is when learning mode last started.
+	learningSince time.Time
 	config        *proto.ResourcePB
 	expiryTime    time.Time
+	decisions     *DecisionLogger
+	clock         Clock
+	// parent is the resource whose capacity this one shares, if any.
+	parent *Resource
+	// group is the borrowing group of the resource, if any.
+	group *borrowGroup
+	// namespace is the namespace of the resource, if any.
+	namespace *namespace
+	// schedule overrides the configured capacity at some times.
+	schedule []*window
+	// capacity is the capacity at the last check, to tell when it
+	// changes with the schedule.
+	capacity int
+	// overcommitUntil is when the last of the leases granted before
+	// the capacity decreased below what the clients had expires.
+	// Until then the clients only get what is free.
+	overcommitUntil time.Time
+	// watchers are signalled when the leases or the configuration
+	// change.
+	watchers map[chan struct{}]bool
+	// lastRequested is protected by the server's mutex.
+	lastRequested time.Time
 }
 
 func (res *Resource) Capacity() int {
 	// zx already expired
-	if !res.expiryTime.IsZero() && res.expiryTime.Before(time.Now()) {
+	if res.expired() {
 		return 0
 	}
-	return int(res.config.GetCapacity())
+	return int(scheduledCapacity(res.schedule, res.config.GetCapacity(), res.clock.Now()))
+}
+
+// CheckCapacity signals the watchers if the capacity changed since
+// the last check, because a schedule window started or ended or the
+// resource expired.
+func (res *Resource) CheckCapacity() {
+	res.mu.Lock()
+	defer res.mu.Unlock()
+	res.checkCapacity()
+}
+
+// checkCapacity does the work of CheckCapacity. If the capacity
+// decreased below what the clients have, the resource is overcommitted
+// until their leases expire. res.mu must be held.
+func (res *Resource) checkCapacity() {
+	capacity := res.Capacity()
+	if capacity == res.capacity {
+		return
+	}
+	if capacity < res.capacity && int(res.store.SumHas()) > capacity {
+		for _, lease := range res.store.Map() {
+			if lease.ExpireTime.After(res.overcommitUntil) {
+				res.overcommitUntil = lease.ExpireTime
+			}
+		}
+	}
+	res.capacity = capacity
+	res.changed()
+	res.reportUsage()
+}
+
+// overcommitted returns true if the clients may have more than the
+// capacity, because it decreased while they held their leases.
+// res.mu must be held.
+func (res *Resource) overcommitted() bool {
+	return res.overcommitUntil.After(res.clock.Now())
+}
+
+// expired returns true if the resource's configuration has expired.
+func (res *Resource) expired() bool {
+	return !res.expiryTime.IsZero() && res.expiryTime.Before(res.clock.Now())
+}
+
+// Expired returns true if the resource's configuration has expired,
+// in which case it has no capacity to give out.
+func (res *Resource) Expired() bool {
+	res.mu.RLock()
+	defer res.mu.RUnlock()
+	return res.expired()
+}
+
+// empty returns true if no client holds a lease on the resource.
+func (res *Resource) empty() bool {
+	res.mu.Lock()
+	defer res.mu.Unlock()
+	res.clean()
+	return res.store.Count() == 0
+}
+
+// Clean removes the expired leases.
+func (res *Resource) Clean() {
+	res.mu.Lock()
+	defer res.mu.Unlock()
+	res.clean()
+}
+
+// clean removes the expired leases, signalling the watchers if there
+// were any. res.mu must be held.
+func (res *Resource) clean() {
+	count := res.store.Count()
+	res.store.Clean()
+	if res.store.Count() != count {
+		res.changed()
+		res.reportToParent()
+		res.reportUsage()
+	}
+}
+
+// reportToParent updates the lease of the resource on its parent, if
+// it has one. res.mu must be held.
+func (res *Resource) reportToParent() {
+	if res.parent == nil {
+		return
+	}
+	res.parent.mu.Lock()
+	defer res.parent.mu.Unlock()
+	res.parent.report(res)
+	res.parent.reportUsage()
+}
+
+// watch makes the resource signal c, without blocking, whenever its
+// leases or its configuration change.
+func (res *Resource) watch(c chan struct{}) {
+	res.mu.Lock()
+	defer res.mu.Unlock()
+	if res.watchers == nil {
+		res.watchers = make(map[chan struct{}]bool)
+	}
+	res.watchers[c] = true
+}
+
+func (res *Resource) unwatch(c chan struct{}) {
+	res.mu.Lock()
+	defer res.mu.Unlock()
+	delete(res.watchers, c)
+}
+
+// changed signals the watchers. res.mu must be held.
+func (res *Resource) changed() {
+	for c := range res.watchers {
+		select {
+		case c <- struct{}{}:
+		default:
+		}
+	}
 }
 
 func (res *Resource) Release(clientId string) {
 	res.mu.Lock()
 	defer res.mu.Unlock()
 	res.store.Release(clientId)
+	res.changed()
+	res.reportToParent()
+	res.reportUsage()
 }
 
 func (res *Resource) Decide(request *Request) Lease {
 	res.mu.Lock()
 	defer res.mu.Unlock()
-	res.store.Clean()
+	res.clean()
+	res.checkCapacity()
 
-	if res.learningEndAt.After(time.Now()) {
-		return res.learnerAlgo(res.store, res.Capacity(), request)
+	old := res.store.Get(request.ClientId)
+	lease, learning := res.decide(request)
+	res.reportUsage()
+	res.decisions.logDecision(res, request, lease, learning)
+	if old.IsZero() || old.Has != lease.Has || old.Want != lease.Want {
+		res.changed()
 	}
-	return res.algo(res.store, res.Capacity(), request)
+	return lease
 }
 
+// decide runs the algorithm for request and returns the lease it
+// assigned and whether the resource is in learning mode. res.mu
+// must be held.
+func (res *Resource) decide(request *Request) (lease Lease, learning bool) {
+	if res.expired() {
+		// Whatever the algorithm, there is nothing to give out.
+		leaseLength, refreshInterval := getAlgorithmParams(res.config.GetAlgo())
+		return res.store.Assign(request.ClientId, leaseLength, refreshInterval, 0, request.Want), false
+	}
+
+	if res.parent != nil {
+		// The children of a parent decide one at a time, so that
+		// together they never give out more than its capacity.
+		res.parent.mu.Lock()
+		defer res.parent.mu.Unlock()
+		defer func() {
+			res.parent.report(res)
+			res.parent.reportUsage()
+		}()
+	}
+	ns := res.quota()
+	if ns != nil {
+		// Likewise for the resources of a namespace.
+		ns.mu.Lock()
+		defer ns.mu.Unlock()
+		defer func() { ns.record(res.resourceId, res.store.SumHas()) }()
+	}
+	if res.group != nil {
+		// And for the members of a borrowing group, so that they never
+		// lend the same capacity twice.
+		res.group.mu.Lock()
+		defer res.group.mu.Unlock()
+		defer func() { res.group.record(res.resourceId, res.usage()) }()
+	}
+
+	capacity := res.Capacity()
+	if res.group != nil {
+		// What the other members of the group do not want, or less
+		// than the resource's own capacity while they have some of it.
+		capacity += int(res.group.borrowable(res.resourceId))
+		if capacity < 0 {
+			capacity = 0
+		}
+	}
+	if res.parent != nil {
+		if share := res.parent.share(res, request); share < capacity {
+			capacity = share
+		}
+	}
+	if ns != nil {
+		if free, ok := ns.free(res.resourceId); ok && free < capacity {
+			capacity = free
+		}
+	}
+
+	if res.inLearningMode() {
+		return res.learnerAlgo(res.store, capacity, request), true
+	}
+	// The floors of the other clients are not for the algorithm to
+	// give out.
+	available := capacity - res.reserved(request.ClientId)
+	if available < 0 {
+		available = 0
+	}
+	request.Weight = res.weight(request)
+	lease = res.bound(request, res.algo(res.store, available, request), capacity)
+	// The clients of an overcommitted resource, or of a member of a
+	// borrowing group, only get what the leases granted before the
+	// capacity decreased leave.
+	if res.parent != nil || ns != nil || res.group != nil || res.overcommitted() {
+		lease = res.limit(request.ClientId, lease, capacity)
+	}
+	return lease, false
+}
+
+// share returns how much of the resource's capacity the child
+// resource can give out in total once it decides request. It is what
+// the resource's algorithm assigns to the child as a client, but never
+// more than what the other children leave. res.mu and child.mu must be
+// held.
+func (res *Resource) share(child *Resource, request *Request) int {
+	res.clean()
+	old := child.store.Get(request.ClientId)
+	lease, _ := res.decide(&Request{
+		ClientId: child.resourceId,
+		Has:      child.store.SumHas(),
+		Want:     child.store.SumWant() - old.Want + request.Want,
+	})
+
+	share := int32(res.Capacity()) - (res.store.SumHas() - lease.Has)
+	if lease.Has < share {
+		share = lease.Has
+	}
+	if share < 0 {
+		share = 0
+	}
+	return int(share)
+}
+
+// report records what the child resource gives out as its lease on
+// the resource. The lease lasts as long as the child's leases, so that
+// it does not expire while they are held. res.mu and child.mu must be
+// held.
+func (res *Resource) report(child *Resource) {
+	old := res.store.Get(child.resourceId)
+	leaseLength, refreshInterval := getAlgorithmParams(child.config.GetAlgo())
+	lease := res.store.Assign(child.resourceId, leaseLength, refreshInterval, child.store.SumHas(), child.store.SumWant())
+	if old.IsZero() || old.Has != lease.Has || old.Want != lease.Want {
+		res.changed()
+	}
+}
+
+// limit reduces lease, just assigned to clientId, so that the leases
+// of the resource do not add up to more than capacity. res.mu must
+// be held.
+func (res *Resource) limit(clientId string, lease Lease, capacity int) Lease {
+	max := int32(capacity) - (res.store.SumHas() - lease.Has)
+	if max < 0 {
+		max = 0
+	}
+	if lease.Has <= max {
+		return lease
+	}
+	leaseLength, refreshInterval := getAlgorithmParams(res.config.GetAlgo())
+	return res.store.Assign(clientId, leaseLength, refreshInterval, max, lease.Want)
+}
+
+// setParent makes the resource share the capacity of parent, or of
+// no other resource if it is nil.
+func (res *Resource) setParent(parent *Resource) {
+	res.mu.Lock()
+	defer res.mu.Unlock()
+	res.parent = parent
+	// Children count in their parent's namespace usage.
+	if res.namespace != nil && parent != nil {
+		res.namespace.remove(res.resourceId)
+	} else {
+		res.reportUsage()
+	}
+}
+
+// parentId returns the id of the resource whose capacity the resource
+// shares, or "" if there is none.
+func (res *Resource) parentId() string {
+	res.mu.RLock()
+	defer res.mu.RUnlock()
+	return res.parentIdLocked()
+}
+
+// parentIdLocked does the work of parentId. res.mu must be held.
+func (res *Resource) parentIdLocked() string {
+	if res.parent == nil {
+		return ""
+	}
+	return res.parent.resourceId
+}
+
+// learningModeLength returns how long learning mode lasts for the
+// resources configured with algo: its learning mode length, or its
+// lease length if not set. It is capped at the lease length, after
+// which the clients have either reported in or lost their leases.
+func learningModeLength(algo *proto.AlgorithmPB) time.Duration {
+	length := algo.GetLeaseLength()
+	if l := algo.GetLearningModeLength(); l != 0 && l < length {
+		length = l
+	}
+	if length < 0 {
+		return 0
+	}
+	return time.Duration(length) * time.Second
+}
+
+// startLearning puts the resource in learning mode from since, when
+// the server became the master.
+func (res *Resource) startLearning(since time.Time) {
+	res.mu.Lock()
+	defer res.mu.Unlock()
+	res.learningSince = since
+	res.learningEndAt = since.Add(learningModeLength(res.config.GetAlgo()))
+	res.changed()
+}
+
+// InLearningMode returns true if the resource is in learning mode.
+func (res *Resource) InLearningMode() bool {
+	res.mu.RLock()
+	defer res.mu.RUnlock()
+	return res.inLearningMode()
+}
+
+// inLearningMode does the work of InLearningMode. res.mu must be
+// held.
+func (res *Resource) inLearningMode() bool {
+	return res.learningEndAt.After(res.clock.Now())
+}
+
+// LoadConfig sets the configuration of the resource. A nil
+// expireTime means the resource never expires.
 func (res *Resource) LoadConfig(cfg *proto.ResourcePB, expireTime *time.Time) {
 	res.mu.Lock()
 	defer res.mu.Unlock()
 	res.config = cfg
-	res.expiryTime = *expireTime
+	res.expiryTime = time.Time{}
+	if expireTime != nil {
+		res.expiryTime = *expireTime
+	}
 	algo := cfg.GetAlgo()
-	res.algo = algoMapper[algo.GetKind()](algo)
+	res.algo = algorithmFor(algo)
 	res.learnerAlgo = Learn(algo)
+	if cfg == nil {
+		// NO_ALGORITHM would give whatever is asked for a resource
+		// nobody configured.
+		res.algo, res.learnerAlgo = noCapacity(algo), noCapacity(algo)
+	}
+	// The learning mode length may have changed.
+	if !res.learningSince.IsZero() {
+		res.learningEndAt = res.learningSince.Add(learningModeLength(algo))
+	}
+	schedule, err := parseSchedule(cfg.GetSchedule())
+	if err != nil {
+		log.Printf("resource %v: ignoring the schedule: %v", res.resourceId, err)
+	}
+	res.schedule = schedule
+	res.checkCapacity()
+	res.changed()
 
 }
 
@@ -64,9 +428,13 @@ func (res *Resource) SetSafeCapacity(resp *proto.GetCapacityResponse_ResourceRes
 	// the capacity divided by the number of clients that we
 	// know about.
 	// needs to take sub clients into account (in a multi-server tree).
-	if res.config.SafeCapacity == 0 {
-		resp.SafeCapacity = *goproto.Int32(res.config.Capacity / int32(res.store.Count()))
+	if res.expired() {
+		resp.SafeCapacity = 0
+	} else if res.config.GetSafeCapacity() == 0 {
+		if count := res.store.Count(); count > 0 {
+			resp.SafeCapacity = *goproto.Int32(int32(res.Capacity()) / count)
+		}
 	} else {
-		resp.SafeCapacity = *goproto.Int32(res.config.SafeCapacity)
+		resp.SafeCapacity = *goproto.Int32(res.config.GetSafeCapacity())
 	}
 }
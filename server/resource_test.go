@@ -0,0 +1,327 @@
+package doorman
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/notfresh/zxdoorman/proto"
+	"golang.org/x/net/context"
+)
+
+func getCapacity(t *testing.T, server *Server, client, resource string, has, wants int32) *proto.GetCapacityResponse_ResourceResponse {
+	t.Helper()
+	out, err := server.GetCapacity(context.Background(), &proto.GetCapacityRequest{
+		ClientId: client,
+		Resource: []*proto.GetCapacityRequest_ResourceRequest{
+			{
+				ResourceId: resource,
+				Has:        &proto.Lease{Capacity: has},
+				Want:       wants,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("GetCapacity: %v", err)
+	}
+	return out.Response[0]
+}
+
+func TestResourceExpiry(t *testing.T) {
+	clock := NewManualClock(time.Unix(1000000, 0))
+	algo := &proto.AlgorithmPB{
+		Kind:        proto.AlgorithmPB_NO_ALGORITHM,
+		LeaseLength: 60,
+	}
+	server, err := MakeTestServerWithClock(clock,
+		&proto.ResourcePB{
+			IdentifierGlob: "forever",
+			Capacity:       100,
+			Algo:           algo,
+		},
+		&proto.ResourcePB{
+			IdentifierGlob: "expired",
+			Capacity:       100,
+			Algo:           algo,
+			ExpiryTime:     clock.Now().Add(-time.Minute).Unix(),
+		},
+		&proto.ResourcePB{
+			IdentifierGlob: "ttl",
+			Capacity:       100,
+			Algo:           algo,
+			Ttl:            3600,
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	for _, c := range []struct {
+		resource string
+		capacity int32
+		expired  bool
+	}{
+		{"forever", 10, false},
+		{"ttl", 10, false},
+		{"expired", 0, true},
+		// A resource without configuration must not crash the
+		// server, and gives nothing.
+		{"unknown", 0, false},
+	} {
+		resp := getCapacity(t, server, "client", c.resource, 10, 10)
+		if got := resp.GetGets().GetCapacity(); got != c.capacity {
+			t.Errorf("%v: capacity = %v want %v", c.resource, got, c.capacity)
+		}
+		if got := resp.GetExpired(); got != c.expired {
+			t.Errorf("%v: expired = %v want %v", c.resource, got, c.expired)
+		}
+	}
+
+	if got := server.getOrCreateResource("ttl").Capacity(); got != 100 {
+		t.Errorf("ttl: Capacity() = %v want 100", got)
+	}
+	if got := server.getOrCreateResource("expired").Capacity(); got != 0 {
+		t.Errorf("expired: Capacity() = %v want 0", got)
+	}
+
+	// The TTL counts from the time the configuration was loaded.
+	clock.Advance(time.Hour)
+	if got := server.getOrCreateResource("ttl").Capacity(); got != 100 {
+		t.Errorf("ttl: Capacity() = %v at expiry time, want 100", got)
+	}
+	clock.Advance(time.Second)
+	resp := getCapacity(t, server, "client", "ttl", 10, 10)
+	if !resp.GetExpired() || resp.GetGets().GetCapacity() != 0 {
+		t.Errorf("ttl: got %v after the TTL, want an expired resource with no capacity", resp)
+	}
+}
+
+func TestResourceExpiryOverride(t *testing.T) {
+	clock := NewManualClock(time.Unix(1000000, 0))
+	server, err := MakeTestServerWithClock(clock, &proto.ResourcePB{
+		IdentifierGlob: "*",
+		Capacity:       100,
+		Algo:           &proto.AlgorithmPB{LeaseLength: 60},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	if resp := getCapacity(t, server, "client", "res", 10, 10); resp.GetExpired() {
+		t.Fatalf("res expired before the override")
+	}
+
+	past := clock.Now().Add(-time.Second)
+	if err := server.LoadConfig(context.Background(), server.config, map[string]*time.Time{"res": &past}); err != nil {
+		t.Fatal(err)
+	}
+	resp := getCapacity(t, server, "client", "res", 10, 10)
+	if !resp.GetExpired() || resp.GetGets().GetCapacity() != 0 {
+		t.Errorf("after the override got %v, want an expired resource with no capacity", resp)
+	}
+}
+
+func TestParentCapacity(t *testing.T) {
+	clock := NewManualClock(time.Unix(1000000, 0))
+	algo := &proto.AlgorithmPB{LeaseLength: 60}
+	server, err := MakeTestServerWithClock(clock,
+		&proto.ResourcePB{IdentifierGlob: "db-writes", Capacity: 100, Algo: algo},
+		&proto.ResourcePB{IdentifierGlob: "db-writes/*", Capacity: 80, Algo: algo, Parent: "db-writes"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	// Gets out of learning mode.
+	clock.Advance(2 * time.Minute)
+
+	for _, c := range []struct {
+		client, resource string
+		wants, gets      int32
+	}{
+		{"a", "db-writes/users", 70, 70},
+		// Only 30 are left in the parent.
+		{"b", "db-writes/orders", 50, 30},
+		{"c", "db-writes/users", 20, 0},
+		// Once a gives some back the others can have it.
+		{"a", "db-writes/users", 10, 10},
+		{"b", "db-writes/orders", 50, 50},
+		{"c", "db-writes/users", 20, 20},
+	} {
+		got := getCapacity(t, server, c.client, c.resource, 0, c.wants).GetGets().GetCapacity()
+		if got != c.gets {
+			t.Errorf("%v wanting %v of %v got %v, want %v", c.client, c.wants, c.resource, got, c.gets)
+		}
+	}
+
+	parent := server.getOrCreateResource("db-writes").Status()
+	if want := int32(80); parent.SumHas != want {
+		t.Errorf("the children of the parent have %v, want %v", parent.SumHas, want)
+	}
+	if want := "db-writes"; server.getOrCreateResource("db-writes/users").Status().Parent != want {
+		t.Errorf("db-writes/users has no parent %v", want)
+	}
+
+	// The parent is not evicted while its children hold leases.
+	server.evictIdleResources(clock.Now().Add(defaultResourceIdleTime))
+	server.mu.RLock()
+	_, ok := server.resources["db-writes"]
+	server.mu.RUnlock()
+	if !ok {
+		t.Errorf("the parent was evicted")
+	}
+}
+
+func TestValidateParents(t *testing.T) {
+	server, err := MakeTestServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	for _, resources := range [][]*proto.ResourcePB{
+		// A typo in the parent.
+		{
+			{IdentifierGlob: "db-writes", Capacity: 100},
+			{IdentifierGlob: "db-writes/*", Capacity: 80, Parent: "db-write"},
+		},
+		// A parent with a parent.
+		{
+			{IdentifierGlob: "db", Capacity: 100},
+			{IdentifierGlob: "db-writes", Capacity: 100, Parent: "db"},
+			{IdentifierGlob: "db-writes/*", Capacity: 80, Parent: "db-writes"},
+		},
+	} {
+		err := server.LoadConfig(context.Background(), &proto.ResourceRepository{Resources: resources}, nil)
+		if err == nil {
+			t.Errorf("resources %v: no error", resources)
+		}
+	}
+	// Parents are looked up in the namespace of their children.
+	err = server.LoadConfig(context.Background(), &proto.ResourceRepository{
+		Resources: []*proto.ResourcePB{{IdentifierGlob: "db-writes", Capacity: 100}},
+		Namespaces: []*proto.NamespacePB{{
+			Name:      "team",
+			Resources: []*proto.ResourcePB{{IdentifierGlob: "db-writes/*", Capacity: 80, Parent: "db-writes"}},
+		}},
+	}, nil)
+	if err == nil {
+		t.Errorf("a parent outside the namespace was accepted")
+	}
+}
+
+func TestOvercommitProtection(t *testing.T) {
+	clock := NewManualClock(time.Unix(1000000, 0))
+	server, err := MakeTestServerWithClock(clock, repositoryWithCapacity(100).Resources...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	// Gets out of learning mode.
+	clock.Advance(2 * time.Minute)
+
+	getCapacity(t, server, "a", "res", 0, 60)
+	getCapacity(t, server, "b", "res", 0, 40)
+	clock.Advance(10 * time.Second)
+	getCapacity(t, server, "b", "res", 40, 40)
+	drained := clock.Now().Add(time.Minute)
+
+	if err := server.LoadConfig(context.Background(), repositoryWithCapacity(50), nil); err != nil {
+		t.Fatal(err)
+	}
+	res := server.getOrCreateResource("res")
+	if status := res.Status(); !status.Overcommitted || !status.OvercommitUntil.Equal(drained) {
+		t.Errorf("overcommitted = %v until %v, want true until %v", status.Overcommitted, status.OvercommitUntil, drained)
+	} else if status.Borrowed != 0 {
+		t.Errorf("borrowed %v without a borrowing group, want 0", status.Borrowed)
+	}
+
+	for _, c := range []struct {
+		client      string
+		wants, gets int32
+	}{
+		// Nothing is free.
+		{"c", 10, 0},
+		// a gives back 50 of its 60, which is what b keeps.
+		{"a", 60, 10},
+		{"c", 10, 0},
+		{"b", 40, 40},
+	} {
+		if got := getCapacity(t, server, c.client, "res", 0, c.wants).GetGets().GetCapacity(); got != c.gets {
+			t.Errorf("%v wanting %v got %v, want %v", c.client, c.wants, got, c.gets)
+		}
+	}
+
+	// Once the old leases expired the algorithm decides alone again.
+	clock.Set(drained.Add(time.Second))
+	if res.Status().Overcommitted {
+		t.Errorf("still overcommitted after the leases expired")
+	}
+	if got := getCapacity(t, server, "c", "res", 0, 100).GetGets().GetCapacity(); got != 100 {
+		t.Errorf("c got %v, want 100", got)
+	}
+}
+
+func TestLearningMode(t *testing.T) {
+	clock := NewManualClock(time.Unix(1000000, 0))
+	algo := &proto.AlgorithmPB{LeaseLength: 60, LearningModeLength: 30}
+	server, err := MakeTestServerWithClock(clock, &proto.ResourcePB{IdentifierGlob: "*", Capacity: 100, Algo: algo})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	// Clients keep what they have, but cannot take more than the
+	// capacity by over-reporting.
+	for _, c := range []struct {
+		client           string
+		has, wants, gets int32
+	}{
+		{"a", 80, 100, 80},
+		{"b", 500, 500, 20},
+		{"c", 10, 5, 0},
+	} {
+		resp := getCapacity(t, server, c.client, "res", c.has, c.wants)
+		if got := resp.GetGets().GetCapacity(); got != c.gets {
+			t.Errorf("%v having %v got %v, want %v", c.client, c.has, got, c.gets)
+		}
+		if !resp.GetLearningMode() {
+			t.Errorf("%v: not in learning mode", c.client)
+		}
+	}
+	if want, got := int32(605), server.getOrCreateResource("res").Status().SumWant; want != got {
+		t.Errorf("sum want = %v, want %v", got, want)
+	}
+
+	clock.Advance(31 * time.Second)
+	if getCapacity(t, server, "a", "res", 80, 100).GetLearningMode() {
+		t.Errorf("still in learning mode after its length")
+	}
+
+	// Becoming the master again restarts learning mode.
+	server.SetMaster(false)
+	server.SetMaster(true)
+	if !getCapacity(t, server, "a", "res", 80, 100).GetLearningMode() {
+		t.Errorf("not in learning mode after becoming the master")
+	}
+	if metrics := scrape(t, server); !strings.Contains(metrics, "doorman_learning_mode_restarts_total 1") {
+		t.Errorf("learning mode restart not in the metrics")
+	}
+
+	// A reloaded configuration changes the length, capped at the
+	// lease length.
+	if err := server.LoadConfig(context.Background(), &proto.ResourceRepository{
+		Resources: []*proto.ResourcePB{{
+			IdentifierGlob: "*",
+			Capacity:       100,
+			Algo:           &proto.AlgorithmPB{LeaseLength: 60, LearningModeLength: 120},
+		}},
+	}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if want, got := clock.Now().Add(time.Minute), server.getOrCreateResource("res").Status().LearningEndAt; !want.Equal(got) {
+		t.Errorf("learning mode ends at %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,212 @@
+package doorman
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/notfresh/zxdoorman/proto"
+)
+
+// clientRule returns the first client rule of the resource's
+// configuration matching client, or nil if there is none. res.mu
+// must be held.
+func (res *Resource) clientRule(client string) *proto.ClientRulePB {
+	for _, rule := range res.config.GetClientRules() {
+		if matched, _ := filepath.Match(rule.GetClientGlob(), client); matched {
+			return rule
+		}
+	}
+	return nil
+}
+
+// weight returns the weight of the client of request: the weight of
+// its client rule if there is one, the weight it asks for otherwise.
+// res.mu must be held.
+func (res *Resource) weight(request *Request) float64 {
+	if w := res.clientRule(request.ClientId).GetWeight(); w > 0 {
+		return w
+	}
+	return request.Weight
+}
+
+// reserved returns the part of the floors of the clients other than
+// client that they do not have yet, which is not for the algorithm
+// to give out. res.mu must be held.
+func (res *Resource) reserved(client string) int {
+	if len(res.config.GetClientRules()) == 0 {
+		return 0
+	}
+	reserved := 0
+	for id, lease := range res.store.Map() {
+		if id == client {
+			continue
+		}
+		floor := res.clientRule(id).GetMinCapacity()
+		if lease.Want < floor {
+			floor = lease.Want
+		}
+		if floor > lease.Has {
+			reserved += int(floor - lease.Has)
+		}
+	}
+	return reserved
+}
+
+// bound applies the rule matching the client of request to lease,
+// just assigned by the algorithm out of capacity. Floors are only
+// raised to with what the other clients leave of capacity, as a rule
+// can match more clients than its floor fits. res.mu must be held.
+func (res *Resource) bound(request *Request, lease Lease, capacity int) Lease {
+	rule := res.clientRule(request.ClientId)
+	if rule == nil {
+		return lease
+	}
+	has := lease.Has
+	if floor := rule.GetMinCapacity(); has < floor {
+		has = floor
+		if request.Want < has {
+			has = request.Want
+		}
+		if free := int32(capacity) - (res.store.SumHas() - lease.Has); has > free {
+			has = free
+		}
+		if has < lease.Has {
+			has = lease.Has
+		}
+	}
+	if ceiling := rule.GetMaxCapacity(); ceiling > 0 && has > ceiling {
+		has = ceiling
+	}
+	if has == lease.Has {
+		return lease
+	}
+	leaseLength, refreshInterval := getAlgorithmParams(res.config.GetAlgo())
+	return res.store.Assign(request.ClientId, leaseLength, refreshInterval, has, lease.Want)
+}
+
+// clientFloors returns what the floors of the client rules of cfg
+// add up to.
+func clientFloors(cfg *proto.ResourcePB) int32 {
+	var floors int32
+	for _, rule := range cfg.GetClientRules() {
+		floors += rule.GetMinCapacity()
+	}
+	return floors
+}
+
+// validateResourceRepository returns an error if config cannot be
+// loaded.
+func validateResourceRepository(config *proto.ResourceRepository) error {
+	if err := validateResources(config.GetResources()); err != nil {
+		return err
+	}
+	for _, glob := range config.GetAdministrators() {
+		if _, err := filepath.Match(glob, ""); err != nil {
+			return fmt.Errorf("administrator %q: %v", glob, err)
+		}
+	}
+	names := make(map[string]bool)
+	for _, ns := range config.GetNamespaces() {
+		name := ns.GetName()
+		if name == "" || strings.Contains(name, namespaceSeparator) {
+			return fmt.Errorf("bad namespace name %q", name)
+		}
+		if names[name] {
+			return fmt.Errorf("namespace %q appears twice", name)
+		}
+		names[name] = true
+		if ns.GetCapacity() < 0 {
+			return fmt.Errorf("namespace %q: negative capacity %v", name, ns.GetCapacity())
+		}
+		for _, glob := range ns.GetAdministrators() {
+			if _, err := filepath.Match(glob, ""); err != nil {
+				return fmt.Errorf("namespace %q: administrator %q: %v", name, glob, err)
+			}
+		}
+		if err := validateResources(ns.GetResources()); err != nil {
+			return fmt.Errorf("namespace %q: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// validateResources returns an error if the configuration of any of
+// resources is invalid.
+func validateResources(resources []*proto.ResourcePB) error {
+	if err := validateResourceConfigs(resources); err != nil {
+		return err
+	}
+	// A typo in a parent would otherwise create an unconfigured parent
+	// starving its children.
+	templates := compileTemplates(&proto.ResourceRepository{Resources: resources})
+	for _, cfg := range resources {
+		parentId := cfg.GetParent()
+		if parentId == "" {
+			continue
+		}
+		parent := matchConfig(resources, templates, parentId)
+		if parent == nil {
+			return fmt.Errorf("resource %q: parent %q matches no configured resource", cfg.GetIdentifierGlob(), parentId)
+		}
+		if parent.GetParent() != "" {
+			return fmt.Errorf("resource %q: parent %q has a parent itself", cfg.GetIdentifierGlob(), parentId)
+		}
+	}
+	return nil
+}
+
+// validateResourceConfigs returns an error if any of the resource
+// configurations is invalid on its own.
+func validateResourceConfigs(resources []*proto.ResourcePB) error {
+	for _, cfg := range resources {
+		glob := cfg.GetIdentifierGlob()
+		if _, err := filepath.Match(glob, ""); err != nil {
+			return fmt.Errorf("resource %q: %v", glob, err)
+		}
+		if isTemplate(cfg) {
+			if _, err := compileTemplate(cfg); err != nil {
+				return fmt.Errorf("resource %q: %v", glob, err)
+			}
+		}
+		if cfg.GetAlgo().GetKind() == proto.AlgorithmPB_TOKEN_BUCKET {
+			if _, err := intParam(cfg.GetAlgo(), burstSizeParam); err != nil {
+				return fmt.Errorf("resource %q: %v", glob, err)
+			}
+		}
+		for _, rule := range cfg.GetClientRules() {
+			if _, err := filepath.Match(rule.GetClientGlob(), ""); err != nil {
+				return fmt.Errorf("resource %q: client rule %q: %v", glob, rule.GetClientGlob(), err)
+			}
+			min, max := rule.GetMinCapacity(), rule.GetMaxCapacity()
+			if min < 0 || max < 0 {
+				return fmt.Errorf("resource %q: client rule %q: negative capacity", glob, rule.GetClientGlob())
+			}
+			if rule.GetWeight() < 0 {
+				return fmt.Errorf("resource %q: client rule %q: negative weight", glob, rule.GetClientGlob())
+			}
+			if max > 0 && min > max {
+				return fmt.Errorf("resource %q: client rule %q: min_capacity %v is more than max_capacity %v", glob, rule.GetClientGlob(), min, max)
+			}
+		}
+		floors := clientFloors(cfg)
+		if floors > cfg.GetCapacity() {
+			return fmt.Errorf("resource %q: the client floors add up to %v, more than the capacity %v", glob, floors, cfg.GetCapacity())
+		}
+		for _, o := range cfg.GetOverrides() {
+			if floors > o.GetCapacity() {
+				return fmt.Errorf("resource %q: the client floors add up to %v, more than the capacity %v of the override of {%v} %q", glob, floors, o.GetCapacity(), o.GetCapture(), o.GetValue())
+			}
+		}
+		windows, err := parseSchedule(cfg.GetSchedule())
+		if err != nil {
+			return fmt.Errorf("resource %q: %v", glob, err)
+		}
+		for _, w := range windows {
+			if floors > w.capacity {
+				return fmt.Errorf("resource %q: the client floors add up to %v, more than the scheduled capacity %v", glob, floors, w.capacity)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package doorman
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/notfresh/zxdoorman/proto"
+	"golang.org/x/net/context"
+)
+
+func TestClientRules(t *testing.T) {
+	clock := NewManualClock(time.Unix(1000000, 0))
+	rules := []*proto.ClientRulePB{
+		{ClientGlob: "health-*", MinCapacity: 5},
+		{ClientGlob: "batch-*", MaxCapacity: 20},
+	}
+	server, err := MakeTestServerWithClock(clock,
+		&proto.ResourcePB{
+			IdentifierGlob: "greedy",
+			Capacity:       100,
+			Algo:           &proto.AlgorithmPB{LeaseLength: 60},
+			ClientRules:    rules,
+		},
+		&proto.ResourcePB{
+			IdentifierGlob: "stingy",
+			Capacity:       100,
+			// An algorithm that gives nothing.
+			Algo:        &proto.AlgorithmPB{Kind: proto.AlgorithmPB_Kind(42), LeaseLength: 60},
+			ClientRules: rules,
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	// Gets out of learning mode.
+	clock.Advance(2 * time.Minute)
+
+	for _, c := range []struct {
+		client, resource string
+		wants, gets      int32
+	}{
+		{"batch-1", "greedy", 50, 20},
+		{"web-1", "greedy", 50, 50},
+		{"health-1", "stingy", 10, 5},
+		{"health-2", "stingy", 3, 3},
+		{"web-1", "stingy", 10, 0},
+	} {
+		got := getCapacity(t, server, c.client, c.resource, 0, c.wants).GetGets().GetCapacity()
+		if got != c.gets {
+			t.Errorf("%v wanting %v of %v got %v, want %v", c.client, c.wants, c.resource, got, c.gets)
+		}
+	}
+
+	// Floors not held yet are reserved.
+	res := server.getOrCreateResource("greedy")
+	getCapacity(t, server, "health-1", "greedy", 0, 0)
+	getCapacity(t, server, "health-2", "greedy", 0, 10)
+	res.mu.RLock()
+	reserved := res.reserved("web-1")
+	res.mu.RUnlock()
+	// health-1 wants nothing and health-2 already has its floor.
+	if reserved != 0 {
+		t.Errorf("reserved = %v want 0", reserved)
+	}
+	res.mu.Lock()
+	res.store.Assign("health-3", time.Minute, 0, 1, 10)
+	reserved = res.reserved("web-1")
+	res.mu.Unlock()
+	if reserved != 4 {
+		t.Errorf("reserved = %v want 4", reserved)
+	}
+}
+
+func TestClientFloorsFitCapacity(t *testing.T) {
+	clock := NewManualClock(time.Unix(1000000, 0))
+	server, err := MakeTestServerWithClock(clock, &proto.ResourcePB{
+		IdentifierGlob: "stingy",
+		Capacity:       100,
+		// An algorithm that gives nothing, so that only floors count.
+		Algo:        &proto.AlgorithmPB{Kind: proto.AlgorithmPB_Kind(42), LeaseLength: 60},
+		ClientRules: []*proto.ClientRulePB{{ClientGlob: "hc-*", MinCapacity: 10}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	// Gets out of learning mode.
+	clock.Advance(2 * time.Minute)
+
+	// Many more clients match the rule than its floor fits.
+	var total int32
+	for i := 0; i < 20; i++ {
+		got := getCapacity(t, server, fmt.Sprintf("hc-%d", i), "stingy", 0, 10).GetGets().GetCapacity()
+		want := int32(10)
+		if i >= 10 {
+			want = 0
+		}
+		if got != want {
+			t.Errorf("hc-%d got %v, want %v", i, got, want)
+		}
+		total += got
+	}
+	if total != 100 {
+		t.Errorf("the clients got %v in total, want 100", total)
+	}
+}
+
+func TestValidateClientRules(t *testing.T) {
+	server, err := MakeTestServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	for _, rules := range [][]*proto.ClientRulePB{
+		{{ClientGlob: "a", MinCapacity: 60}, {ClientGlob: "b", MinCapacity: 60}},
+		{{ClientGlob: "a", MinCapacity: 20, MaxCapacity: 10}},
+		{{ClientGlob: "a", MaxCapacity: -1}},
+		{{ClientGlob: "[", MinCapacity: 1}},
+	} {
+		err := server.LoadConfig(context.Background(), &proto.ResourceRepository{
+			Resources: []*proto.ResourcePB{{IdentifierGlob: "*", Capacity: 100, ClientRules: rules}},
+		}, nil)
+		if err == nil {
+			t.Errorf("rules %v: no error", rules)
+		}
+	}
+}
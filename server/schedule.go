@@ -0,0 +1,135 @@
+package doorman
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/notfresh/zxdoorman/proto"
+)
+
+// window is a parsed proto.ScheduleWindowPB.
+type window struct {
+	// days are the days of the week the window starts on, indexed
+	// by time.Weekday.
+	days [7]bool
+	// start and end are minutes since midnight.
+	start, end int
+	loc        *time.Location
+	capacity   int32
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// parseSchedule parses the schedule windows of a resource.
+func parseSchedule(pbs []*proto.ScheduleWindowPB) ([]*window, error) {
+	var windows []*window
+	for i, pb := range pbs {
+		w, err := parseWindow(pb)
+		if err != nil {
+			return nil, fmt.Errorf("schedule window %d: %v", i, err)
+		}
+		windows = append(windows, w)
+	}
+	return windows, nil
+}
+
+func parseWindow(pb *proto.ScheduleWindowPB) (*window, error) {
+	w := &window{capacity: pb.GetCapacity(), loc: time.UTC}
+	if w.capacity < 0 {
+		return nil, fmt.Errorf("negative capacity %v", w.capacity)
+	}
+	var err error
+	if w.days, err = parseDays(pb.GetDays()); err != nil {
+		return nil, err
+	}
+	if w.start, err = parseTimeOfDay(pb.GetStart()); err != nil {
+		return nil, err
+	}
+	if w.end, err = parseTimeOfDay(pb.GetEnd()); err != nil {
+		return nil, err
+	}
+	if tz := pb.GetTimeZone(); tz != "" {
+		if w.loc, err = time.LoadLocation(tz); err != nil {
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+// parseDays parses days like "mon-fri" or "sat,sun".
+func parseDays(s string) (days [7]bool, err error) {
+	if s == "" || s == "*" {
+		for i := range days {
+			days[i] = true
+		}
+		return days, nil
+	}
+	for _, part := range strings.Split(s, ",") {
+		names := strings.SplitN(strings.TrimSpace(part), "-", 2)
+		first, ok := weekdays[strings.ToLower(names[0])]
+		if !ok {
+			return days, fmt.Errorf("unknown day %q", names[0])
+		}
+		last := first
+		if len(names) == 2 {
+			if last, ok = weekdays[strings.ToLower(names[1])]; !ok {
+				return days, fmt.Errorf("unknown day %q", names[1])
+			}
+		}
+		// Ranges can wrap around the end of the week, like fri-mon.
+		for d := first; ; d = (d + 1) % 7 {
+			days[d] = true
+			if d == last {
+				break
+			}
+		}
+	}
+	return days, nil
+}
+
+// parseTimeOfDay parses a time like "15:04" into minutes since
+// midnight.
+func parseTimeOfDay(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("bad time of day %q", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// active returns true if the window is active at t.
+func (w *window) active(t time.Time) bool {
+	t = t.In(w.loc)
+	minute := t.Hour()*60 + t.Minute()
+	today := w.days[t.Weekday()]
+	switch {
+	case w.start == w.end:
+		return today
+	case w.start < w.end:
+		return today && w.start <= minute && minute < w.end
+	default:
+		// The window ends the day after it starts.
+		yesterday := w.days[(t.Weekday()+6)%7]
+		return (today && minute >= w.start) || (yesterday && minute < w.end)
+	}
+}
+
+// scheduledCapacity returns the capacity of the first window of
+// windows active at t, or capacity if there is none.
+func scheduledCapacity(windows []*window, capacity int32, t time.Time) int32 {
+	for _, w := range windows {
+		if w.active(t) {
+			return w.capacity
+		}
+	}
+	return capacity
+}
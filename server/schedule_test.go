@@ -0,0 +1,92 @@
+package doorman
+
+import (
+	"testing"
+	"time"
+
+	"github.com/notfresh/zxdoorman/proto"
+)
+
+func TestWindowActive(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skip(err)
+	}
+	// Nights from Monday to Friday, in Paris.
+	w, err := parseWindow(&proto.ScheduleWindowPB{Days: "mon-fri", Start: "22:00", End: "06:00", TimeZone: "Europe/Paris", Capacity: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		t      time.Time
+		active bool
+	}{
+		{time.Date(2026, 10, 19, 21, 59, 0, 0, paris), false}, // Monday
+		{time.Date(2026, 10, 19, 22, 0, 0, 0, paris), true},
+		{time.Date(2026, 10, 20, 5, 59, 0, 0, paris), true},
+		{time.Date(2026, 10, 20, 6, 0, 0, 0, paris), false},
+		{time.Date(2026, 10, 24, 1, 0, 0, 0, paris), true},   // Saturday, after Friday night
+		{time.Date(2026, 10, 24, 23, 0, 0, 0, paris), false}, // Saturday night
+		{time.Date(2026, 10, 19, 20, 30, 0, 0, time.UTC), true},
+	} {
+		if got := w.active(c.t); got != c.active {
+			t.Errorf("active(%v) = %v want %v", c.t, got, c.active)
+		}
+	}
+}
+
+func TestParseDays(t *testing.T) {
+	days, err := parseDays("fri-mon,wed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [7]bool{true, true, false, true, false, true, true}; days != want {
+		t.Errorf("days = %v want %v", days, want)
+	}
+	for _, bad := range []string{"funday", "mon-"} {
+		if _, err := parseDays(bad); err == nil {
+			t.Errorf("parseDays(%q): no error", bad)
+		}
+	}
+}
+
+func TestScheduledCapacity(t *testing.T) {
+	clock := NewManualClock(time.Date(2026, 10, 19, 21, 59, 59, 0, time.UTC))
+	server, err := MakeTestServerWithClock(clock, &proto.ResourcePB{
+		IdentifierGlob: "*",
+		Capacity:       100,
+		Algo:           &proto.AlgorithmPB{LeaseLength: 60},
+		Schedule: []*proto.ScheduleWindowPB{
+			{Start: "22:00", End: "06:00", Capacity: 1000},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	res := server.getOrCreateResource("batch")
+	wake := make(chan struct{}, 1)
+	res.watch(wake)
+	defer res.unwatch(wake)
+
+	if got := res.Status().Capacity; got != 100 {
+		t.Errorf("capacity %v want 100", got)
+	}
+	// The run loop notices the window starting.
+	for i := 0; ; i++ {
+		clock.Advance(time.Second)
+		select {
+		case <-wake:
+		case <-time.After(time.Millisecond):
+			if i == 1000 {
+				t.Fatalf("the watcher was not signalled")
+			}
+			continue
+		}
+		break
+	}
+	if got := res.Status().Capacity; got != 1000 {
+		t.Errorf("capacity %v want 1000", got)
+	}
+}
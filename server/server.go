@@ -15,14 +15,31 @@ type Server struct {
 	isConfigured   chan bool
 	mu             sync.RWMutex
 	resources      map[string]*Resource
+	groups         map[string]*borrowGroup
+	namespaces     map[string]*namespace
 	isMaster       bool
 	becameMasterAt time.Time
 	currentMaster  string
 	config         *proto.ResourceRepository
+	templates      map[*proto.ResourcePB]*resourceTemplate
+	configLoadedAt time.Time
+	expiryTimes    map[string]*time.Time
+	history        []*configVersion
+	historySize    int
+	idleTime       time.Duration
+	lastVersion    int64
+	metrics        *serverMetrics
+	decisions      *DecisionLogger
+	recorder       *Recorder
+	clock          Clock
 	quit           chan bool
 	proto.UnimplementedCapacityServer
+	proto.UnimplementedAdminServer
 }
 
+// ServerOption configures a Server created with NewServer.
+type ServerOption func(*Server)
+
 //func (server *Server) MustEmbedUnimplementedCapacityServer() {
 //	//TODO implement me
 //	panic("implement me")
@@ -36,26 +53,69 @@ func (server *Server) Close() {
 	server.quit <- true
 }
 
-func (server *Server) GetLearningModeEndTime(learningLength time.Duration) time.Time {
-	if learningLength.Seconds() <= 0 {
-		return time.Unix(0, 0)
+// IsMaster returns true if the server is the master.
+func (server *Server) IsMaster() bool {
+	server.mu.RLock()
+	defer server.mu.RUnlock()
+	return server.isMaster
+}
+
+// SetMaster tells the server whether it is the master. Becoming the
+// master restarts learning mode for every resource: the clients may
+// hold leases granted by the previous master, which the server needs
+// to learn about before deciding.
+func (server *Server) SetMaster(isMaster bool) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if isMaster == server.isMaster {
+		return
+	}
+	server.isMaster = isMaster
+	if !isMaster {
+		return
+	}
+	server.becameMasterAt = server.clock.Now()
+	server.metrics.learningRestarts.Inc()
+	for _, res := range server.resources {
+		res.startLearning(server.becameMasterAt)
 	}
-	return server.becameMasterAt.Add(learningLength)
 }
 
-// the server was created.
+// LoadConfig loads a new configuration, updating the configuration
+// and the expiry time of every known resource. expiryTimes maps a
+// resource id to an explicit expiry time, overriding the expiry_time
+// and ttl in the resource's configuration.
 func (server *Server) LoadConfig(ctx context.Context, config *proto.ResourceRepository, expiryTimes map[string]*time.Time) error {
-	//if err := validateResourceRepository(config); err != nil {
-	//	return err
-	//}
+	if err := validateResourceRepository(config); err != nil {
+		server.ConfigLoadFailed()
+		return err
+	}
 	// zx ? take part in election? How?
 	server.mu.Lock()
 	defer server.mu.Unlock()
 
+	server.loadConfig(config, expiryTimes, 0)
+	server.metrics.configLoads.Inc()
+	return nil
+}
+
+// ConfigLoadFailed records that a configuration could not be loaded,
+// for example because it could not be parsed.
+func (server *Server) ConfigLoadFailed() {
+	server.metrics.configFailures.Inc()
+}
+
+// loadConfig does the work of LoadConfig and records the new
+// configuration in the history. server.mu must be held.
+func (server *Server) loadConfig(config *proto.ResourceRepository, expiryTimes map[string]*time.Time, rollbackOf int64) *configVersion {
 	firstTime := server.config == nil
 
 	// Stores the new configuration in the server object.
 	server.config = config // zx set the config
+	server.templates = compileTemplates(config)
+	server.loadNamespaces(config)
+	server.configLoadedAt = server.clock.Now()
+	server.expiryTimes = expiryTimes
 
 	// If this is the first load of a config there are no resources
 	// in the server map, so no need to process those, but we do need
@@ -71,20 +131,61 @@ func (server *Server) LoadConfig(ctx context.Context, config *proto.ResourceRepo
 	// Goes through the server's map of resources, loads a new
 	// configuration and updates expiration time for each of them.
 	for id, resource := range server.resources { // zx lazy create
-		resource.LoadConfig(server.findConfigForResource(id), expiryTimes[id])
+		cfg := server.findConfigForResource(id)
+		resource.LoadConfig(cfg, server.expiryTime(id, cfg))
+		resource.setNamespace(server.namespaces[server.namespaceOf(id)])
+		resource.setParent(server.parentFor(id, cfg))
+		resource.setGroup(server.borrowGroup(qualify(server.namespaceOf(id), cfg.GetBorrowingGroup())))
 	}
 
-	return nil
+	return server.recordConfig(config, rollbackOf)
 }
 
-func NewServer(ctx context.Context, id string) (*Server, error) {
+// expiryTime returns when the resource id configured with cfg
+// expires, or nil if it never does. TTLs count from the time the
+// configuration was loaded, not from the time the resource was
+// created.
+func (server *Server) expiryTime(id string, cfg *proto.ResourcePB) *time.Time {
+	if t, ok := server.expiryTimes[id]; ok {
+		return t
+	}
+
+	var expiry time.Time
+	if at := cfg.GetExpiryTime(); at > 0 {
+		expiry = time.Unix(at, 0)
+	}
+	if ttl := cfg.GetTtl(); ttl > 0 {
+		t := server.configLoadedAt.Add(time.Duration(ttl) * time.Second)
+		if expiry.IsZero() || t.Before(expiry) {
+			expiry = t
+		}
+	}
+	if expiry.IsZero() {
+		return nil
+	}
+	return &expiry
+}
+
+func NewServer(ctx context.Context, id string, opts ...ServerOption) (*Server, error) {
 	server := &Server{
-		ServerId:       id,
-		isConfigured:   make(chan bool),
-		resources:      make(map[string]*Resource),
-		becameMasterAt: time.Now(),
-		quit:           make(chan bool),
+		ServerId:     id,
+		isConfigured: make(chan bool),
+		resources:    make(map[string]*Resource),
+		groups:       make(map[string]*borrowGroup),
+		namespaces:   make(map[string]*namespace),
+		historySize:  defaultConfigHistory,
+		idleTime:     defaultResourceIdleTime,
+		clock:        realClock{},
+		quit:         make(chan bool),
+	}
+	for _, opt := range opts {
+		opt(server)
 	}
+	// There is no master election: the server is the master from
+	// the start.
+	server.isMaster = true
+	server.becameMasterAt = server.clock.Now()
+	server.metrics = newServerMetrics(server)
 
 	go server.run()
 
@@ -97,29 +198,130 @@ func NewServer(ctx context.Context, id string) (*Server, error) {
 
 var defaultInterval = time.Duration(1 * time.Second)
 
+// defaultResourceIdleTime is how long a resource without leases
+// must go unrequested before it is evicted, unless set with
+// WithResourceIdleTime.
+const defaultResourceIdleTime = time.Minute
+
+// WithResourceIdleTime sets how long a resource without leases must
+// go unrequested before it is evicted. Resources are never evicted if
+// d is not positive.
+func WithResourceIdleTime(d time.Duration) ServerOption {
+	return func(server *Server) {
+		server.idleTime = d
+	}
+}
+
 func (server *Server) run() {
 	for { // zx
 		select {
 		case <-server.quit: // zx wait to check if closed, quit gracefully
 			// The server is closed, nothing to do here.
 			return
+		case now := <-server.clock.After(defaultInterval):
+			server.updateResources()
+			server.evictIdleResources(now)
 		}
 	}
 }
 
+// updateResources removes the expired leases of all the resources and
+// checks whether their capacity changed, so that watchers learn about
+// clients that went away and schedule windows that started or ended.
+func (server *Server) updateResources() {
+	server.mu.RLock()
+	resources := make([]*Resource, 0, len(server.resources))
+	for _, res := range server.resources {
+		resources = append(resources, res)
+	}
+	server.mu.RUnlock()
+
+	for _, res := range resources {
+		res.Clean()
+		res.CheckCapacity()
+	}
+}
+
+// evictIdleResources forgets the resources that have no leases and
+// were not requested for the server's idle time. They are created
+// again on their next request.
+func (server *Server) evictIdleResources(now time.Time) {
+	if server.idleTime <= 0 {
+		return
+	}
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	// Parents stay as long as their children do.
+	parents := make(map[string]bool)
+	for _, res := range server.resources {
+		if id := res.parentId(); id != "" {
+			parents[id] = true
+		}
+	}
+	for id, res := range server.resources {
+		if now.Sub(res.lastRequested) < server.idleTime || parents[id] || !res.empty() {
+			continue
+		}
+		delete(server.resources, id)
+		res.setGroup(nil)
+		res.setNamespace(nil)
+		server.metrics.resourcesGone.Inc()
+	}
+}
+
+// parentFor returns the parent of the resource id configured with
+// cfg, creating it if necessary, or nil if it has none. Parents cannot
+// have parents themselves, and the parents of the resources of a
+// namespace are in the namespace. server.mu must be held.
+func (server *Server) parentFor(id string, cfg *proto.ResourcePB) *Resource {
+	parentId := qualify(server.namespaceOf(id), cfg.GetParent())
+	if parentId == "" {
+		return nil
+	}
+	if parentId == id || server.findConfigForResource(parentId).GetParent() != "" {
+		log.Printf("resource %v cannot have %v as parent: parents cannot have parents", id, parentId)
+		return nil
+	}
+	return server.resource(parentId)
+}
+
+// findConfigForResource returns the configuration of the resource id,
+// nil if there is none. The resources of a namespace are configured by
+// the namespace, matching the id without the name of the namespace.
+// server.mu must be held.
 func (server *Server) findConfigForResource(id string) *proto.ResourcePB {
+	resources := server.config.GetResources()
+	if name, local, ok := splitNamespace(id); ok {
+		if ns := findNamespace(server.config, name); ns != nil {
+			resources, id = ns.GetResources(), local
+		}
+	}
+	return matchConfig(resources, server.templates, id)
+}
+
+// matchConfig returns the configuration of resources matching id, nil
+// if there is none, templates being their compiled templates.
+func matchConfig(resources []*proto.ResourcePB, templates map[*proto.ResourcePB]*resourceTemplate, id string) *proto.ResourcePB {
 	// Try to match it literally.
-	for _, tpl := range server.config.Resources {
+	for _, tpl := range resources {
 		if tpl.GetIdentifierGlob() == id {
 			return tpl
 		}
 	}
-	for _, tpl := range server.config.Resources {
+	for _, tpl := range resources {
+		if isTemplate(tpl) {
+			if t, ok := templates[tpl]; ok {
+				if values, ok := t.match(id); ok {
+					return t.configFor(id, values)
+				}
+			}
+			continue
+		}
 		glob := tpl.GetIdentifierGlob()
 		matched, err := filepath.Match(glob, id)
 
 		if err != nil {
-			log.Println("Error trying to match %v to %v", id, glob)
+			log.Printf("Error trying to match %v to %v", id, glob)
 			continue
 		} else if matched {
 			return tpl
@@ -139,32 +341,53 @@ type clientRequest struct {
 	resID  string
 	has    int32
 	want   int32
+	weight float64
 }
 
 // GetCapacity assigns capacity leases to clients. It is part of the
 // doorman.CapacityServer implementation.
 // zx the core
 func (server *Server) GetCapacity(ctx context.Context, in *proto.GetCapacityRequest) (out *proto.GetCapacityResponse, err error) {
-	out = new(proto.GetCapacityResponse)
-	client := in.GetClientId()
-	// We will create a new goroutine for every resource in the
-	// request. This is the channel that the leases come back on.
-	itemsC := make(chan item, len(in.Resource))
+	defer func(start time.Time) {
+		server.metrics.observeRequest("GetCapacity", start, err)
+	}(time.Now())
+	requests := clientRequests(in)
+	if err := server.checkAccess(requests); err != nil {
+		return nil, err
+	}
+	// Only requests that were decided are worth replaying.
+	if err := server.recorder.Record(server.clock.Now(), in); err != nil {
+		log.Printf("cannot record request: %v", err)
+	}
+	return server.decide(requests), nil
+}
 
+// clientRequests returns the requests for each resource in in.
+func clientRequests(in *proto.GetCapacityRequest) []clientRequest {
 	var requests []clientRequest
 
 	for _, req := range in.Resource {
 		request := clientRequest{
-			client: client,
+			client: in.GetClientId(),
 			resID:  req.GetResourceId(),
 			has:    req.GetHas().GetCapacity(),
 			want:   req.GetWant(),
+			weight: req.GetWeight(),
 		}
 		requests = append(requests, request)
 	}
+	return requests
+}
+
+// decide assigns leases for requests and returns them as a response.
+func (server *Server) decide(requests []clientRequest) *proto.GetCapacityResponse {
+	out := new(proto.GetCapacityResponse)
+	// We will create a new goroutine for every resource in the
+	// request. This is the channel that the leases come back on.
+	itemsC := make(chan item, len(requests))
 	server.getCapacity(requests, itemsC)
 	// We collect the assigned leases.
-	for range in.Resource {
+	for range requests {
 		item := <-itemsC
 		resp := &proto.GetCapacityResponse_ResourceResponse{
 			ResourceId: *goproto.String(item.id),
@@ -172,13 +395,17 @@ func (server *Server) GetCapacity(ctx context.Context, in *proto.GetCapacityRequ
 				RefreshInterval: *goproto.Int64(int64(item.lease.RefreshInterval.Seconds())),
 				ExpiryTime:      *goproto.Int64(item.lease.ExpireTime.Unix()),
 				Capacity:        *goproto.Int32(item.lease.Has),
+				Burst:           item.lease.Burst,
 			},
 		}
-		server.getOrCreateResource(item.id).SetSafeCapacity(resp)
+		res := server.getOrCreateResource(item.id)
+		res.SetSafeCapacity(resp)
+		resp.Expired = res.Expired()
+		resp.LearningMode = res.InLearningMode()
 		out.Response = append(out.Response, resp)
 	}
 
-	return out, nil
+	return out
 }
 
 func (server *Server) getCapacity(crequests []clientRequest, itemsC chan item) {
@@ -188,6 +415,7 @@ func (server *Server) getCapacity(crequests []clientRequest, itemsC chan item) {
 			ClientId: creq.client,
 			Has:      creq.has,
 			Want:     creq.want,
+			Weight:   creq.weight,
 		}
 
 		go func(req Request) {
@@ -204,39 +432,42 @@ func (server *Server) getCapacity(crequests []clientRequest, itemsC chan item) {
 func (server *Server) getOrCreateResource(id string) *Resource {
 	server.mu.Lock()
 	defer server.mu.Unlock()
+	return server.resource(id)
+}
 
+// resource does the work of getOrCreateResource. server.mu must be
+// held.
+func (server *Server) resource(id string) *Resource {
 	// Resource already exists in the server state; return it.
+	// Marking it as requested under server.mu keeps it from being
+	// evicted before the caller is done with it.
 	if res, ok := server.resources[id]; ok {
+		res.lastRequested = server.clock.Now()
 		return res
 	}
 
-	resource := server.newResource(id, server.findConfigForResource(id))
+	cfg := server.findConfigForResource(id)
+	resource := server.newResource(id, cfg, server.expiryTime(id, cfg))
+	resource.lastRequested = server.clock.Now()
 	server.resources[id] = resource
+	server.metrics.resourcesNew.Inc()
 	return resource
 }
 
-// newResource returns a new resource named id and configured using
-// cfg.
-func (server *Server) newResource(id string, cfg *proto.ResourcePB) *Resource {
+// newResource returns a new resource named id, configured using
+// cfg and expiring at expiry.
+func (server *Server) newResource(id string, cfg *proto.ResourcePB, expiry *time.Time) *Resource {
 	res := &Resource{
 		resourceId: id,
-		store:      NewLeaseStore(id),
+		store:      NewLeaseStore(id, server.clock),
+		decisions:  server.decisions,
+		clock:      server.clock,
 	}
-	res.LoadConfig(cfg, nil) // zx load expireTime has no usage.
-
-	// Calculates the learning mode end time. If one was not specified in the
-	// algorithm the learning mode duration equals the lease length, because
-	// that is the maximum time after which we can assume clients to have either
-	// reported in or lost their lease.
-	algo := res.config.GetAlgo()
+	res.LoadConfig(cfg, expiry)
+	res.setNamespace(server.namespaces[server.namespaceOf(id)])
+	res.setParent(server.parentFor(id, cfg))
+	res.setGroup(server.borrowGroup(qualify(server.namespaceOf(id), cfg.GetBorrowingGroup())))
 
-	var learningModeDuration time.Duration
-
-	if algo.GetLearningModeLength() != 0 {
-		learningModeDuration = time.Duration(algo.GetLearningModeLength()) * time.Second
-	} else {
-		learningModeDuration = time.Duration(algo.GetLeaseLength()) * time.Second
-	}
-	res.learningEndAt = server.GetLearningModeEndTime(learningModeDuration)
+	res.startLearning(server.becameMasterAt)
 	return res
 }
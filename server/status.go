@@ -0,0 +1,157 @@
+package doorman
+
+import (
+	"sort"
+	"time"
+
+	"github.com/notfresh/zxdoorman/proto"
+)
+
+// ClientLease is the lease a client holds on a resource.
+type ClientLease struct {
+	ClientId string
+	Lease
+}
+
+// ResourceStatus is a snapshot of the state of a resource.
+type ResourceStatus struct {
+	ID string
+	// Namespace is the namespace of the resource, empty if there is
+	// none.
+	Namespace string
+	// Config is the configuration matching the resource, nil if
+	// there is none.
+	Config *proto.ResourcePB
+	// Parent is the id of the resource whose capacity this one
+	// shares, empty if there is none.
+	Parent string
+	// Group is the borrowing group of the resource, empty if there is
+	// none, and Borrowed what the other members lent it.
+	Group          string
+	Borrowed       int32
+	Capacity       int
+	SumHas         int32
+	SumWant        int32
+	Count          int32
+	LearningEndAt  time.Time
+	InLearningMode bool
+	ExpiryTime     time.Time
+	Expired        bool
+	// OvercommitUntil is when the leases granted before the capacity
+	// decreased below what the clients had expire, and Overcommitted
+	// whether that is still to come.
+	OvercommitUntil time.Time
+	Overcommitted   bool
+	Leases          []ClientLease
+}
+
+// ServerStatus is a snapshot of the state of a server.
+type ServerStatus struct {
+	ID             string
+	IsMaster       bool
+	BecameMasterAt time.Time
+	ConfigVersion  int64
+	ConfigLoadedAt time.Time
+	Namespaces     []NamespaceStatus
+	Resources      []ResourceStatus
+}
+
+// NamespaceStatus is a snapshot of the state of a namespace.
+type NamespaceStatus struct {
+	Name string
+	// Capacity is what the resources of the namespace give out in
+	// total at most, 0 for no limit, and Has what they give out.
+	Capacity int32
+	Has      int32
+}
+
+// Status returns a snapshot of the state of the resource.
+func (res *Resource) Status() ResourceStatus {
+	res.mu.Lock()
+	defer res.mu.Unlock()
+	res.clean()
+	res.checkCapacity()
+
+	status := ResourceStatus{
+		ID:              res.resourceId,
+		Namespace:       res.namespaceName(),
+		Config:          res.config,
+		Parent:          res.parentIdLocked(),
+		Group:           res.groupName(),
+		Capacity:        res.Capacity(),
+		SumHas:          res.store.SumHas(),
+		SumWant:         res.store.SumWant(),
+		Count:           res.store.Count(),
+		LearningEndAt:   res.learningEndAt,
+		InLearningMode:  res.inLearningMode(),
+		ExpiryTime:      res.expiryTime,
+		Expired:         res.expired(),
+		OvercommitUntil: res.overcommitUntil,
+		Overcommitted:   res.overcommitted(),
+	}
+	if res.group != nil {
+		status.Borrowed = res.group.lent(res.resourceId, res.usage())
+	}
+	for clientId, lease := range res.store.Map() {
+		status.Leases = append(status.Leases, ClientLease{ClientId: clientId, Lease: lease})
+	}
+	sort.Slice(status.Leases, func(i, j int) bool {
+		return status.Leases[i].ClientId < status.Leases[j].ClientId
+	})
+	return status
+}
+
+// Status returns a snapshot of the state of the server and all its
+// resources, sorted by id.
+func (server *Server) Status() ServerStatus {
+	server.mu.RLock()
+	status := ServerStatus{
+		ID:             server.ServerId,
+		IsMaster:       server.isMaster,
+		BecameMasterAt: server.becameMasterAt,
+		ConfigVersion:  server.lastVersion,
+		ConfigLoadedAt: server.configLoadedAt,
+	}
+	resources := make([]*Resource, 0, len(server.resources))
+	for _, res := range server.resources {
+		resources = append(resources, res)
+	}
+	namespaces := make([]*namespace, 0, len(server.namespaces))
+	for _, ns := range server.namespaces {
+		namespaces = append(namespaces, ns)
+	}
+	server.mu.RUnlock()
+
+	for _, ns := range namespaces {
+		status.Namespaces = append(status.Namespaces, ns.status())
+	}
+	sort.Slice(status.Namespaces, func(i, j int) bool {
+		return status.Namespaces[i].Name < status.Namespaces[j].Name
+	})
+
+	for _, res := range resources {
+		status.Resources = append(status.Resources, res.Status())
+	}
+	sort.Slice(status.Resources, func(i, j int) bool {
+		return status.Resources[i].ID < status.Resources[j].ID
+	})
+	return status
+}
+
+// InNamespace returns the status restricted to the namespace name and
+// its resources.
+func (status ServerStatus) InNamespace(name string) ServerStatus {
+	namespaces, resources := status.Namespaces, status.Resources
+	status.Namespaces, status.Resources = nil, nil
+	for _, ns := range namespaces {
+		if ns.Name == name {
+			status.Namespaces = append(status.Namespaces, ns)
+		}
+	}
+	for _, res := range resources {
+		if res.Namespace == name {
+			status.Resources = append(status.Resources, res)
+		}
+	}
+	return status
+}
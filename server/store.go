@@ -6,6 +6,12 @@ type Lease struct { // zx a store level
 	Has, Want       int32
 	ExpireTime      time.Time
 	RefreshInterval time.Duration
+	// Weight is the weight of the client in fair share algorithms,
+	// 0 if it was not set.
+	Weight float64
+	// Burst is the capacity the client can use on top of Has, for
+	// algorithms allowing bursts.
+	Burst int32
 }
 
 func (l *Lease) IsZero() bool {
@@ -15,25 +21,34 @@ func (l *Lease) IsZero() bool {
 type LeaseStore interface {
 	Get(clientId string) Lease
 	Assign(clientId string, leaseLength, refreshInterval time.Duration, has, want int32) Lease
+	SetWeight(clientId string, weight float64)
+	SetBurst(clientId string, burst int32)
 	Release(clientId string)
 	Clean()
 	Count() int32 // zx the numbers of clients
 	SumHas() int32
 	SumWant() int32
+	Map() map[string]Lease // a copy of the leases by client id
 }
 
 type leaseStoreImp struct {
 	ResourceId             string
 	leases                 map[string]Lease
 	sumHas, sumWant, count int32
+	clock                  Clock
 }
 
-func NewLeaseStore(resourceId string) LeaseStore {
+// NewLeaseStore returns a store for the leases of resourceId which
+// tells their expiry time with clock, the real time if nil.
+func NewLeaseStore(resourceId string, clock Clock) LeaseStore {
+	if clock == nil {
+		clock = realClock{}
+	}
 	return &leaseStoreImp{
 		ResourceId: resourceId,
 		leases:     make(map[string]Lease),
+		clock:      clock,
 	}
-	//return nil
 }
 
 func (store *leaseStoreImp) Get(clientId string) Lease {
@@ -44,16 +59,32 @@ func (store *leaseStoreImp) Assign(clientId string, leaseLength, refreshInterval
 	lease, ok := store.leases[clientId]
 	store.sumHas += has - lease.Has
 	store.sumWant += want - lease.Want
-	if ok {
-		store.count += 1 // TODO zx
+	if !ok {
+		store.count++
 	}
 	lease.Has, lease.Want = has, want
-	lease.ExpireTime = time.Now().Add(leaseLength)
+	lease.ExpireTime = store.clock.Now().Add(leaseLength)
 	lease.RefreshInterval = refreshInterval
 	store.leases[clientId] = lease
 	return lease
 }
 
+// SetWeight sets the weight of the lease of clientId, if it has one.
+func (store *leaseStoreImp) SetWeight(clientId string, weight float64) {
+	if lease, ok := store.leases[clientId]; ok {
+		lease.Weight = weight
+		store.leases[clientId] = lease
+	}
+}
+
+// SetBurst sets the burst of the lease of clientId, if it has one.
+func (store *leaseStoreImp) SetBurst(clientId string, burst int32) {
+	if lease, ok := store.leases[clientId]; ok {
+		lease.Burst = burst
+		store.leases[clientId] = lease
+	}
+}
+
 func (store *leaseStoreImp) Release(clientId string) {
 	lease, ok := store.leases[clientId]
 	if !ok {
@@ -61,11 +92,12 @@ func (store *leaseStoreImp) Release(clientId string) {
 	}
 	store.sumHas -= lease.Has
 	store.sumWant -= lease.Want
+	store.count--
 	delete(store.leases, clientId)
 }
 
 func (store *leaseStoreImp) Clean() {
-	when := time.Now()
+	when := store.clock.Now()
 	for clientId, lease := range store.leases {
 		if when.After(lease.ExpireTime) {
 			store.Release(clientId)
@@ -74,7 +106,7 @@ func (store *leaseStoreImp) Clean() {
 }
 
 func (store *leaseStoreImp) Count() int32 {
-	return 0
+	return store.count
 }
 
 func (store *leaseStoreImp) SumHas() int32 {
@@ -84,3 +116,11 @@ func (store *leaseStoreImp) SumHas() int32 {
 func (store *leaseStoreImp) SumWant() int32 {
 	return store.sumWant
 }
+
+func (store *leaseStoreImp) Map() map[string]Lease {
+	leases := make(map[string]Lease, len(store.leases))
+	for clientId, lease := range store.leases {
+		leases[clientId] = lease
+	}
+	return leases
+}
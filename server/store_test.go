@@ -6,33 +6,49 @@ import (
 )
 
 func TestStore(t *testing.T) {
-	store := NewLeaseStore("test")
+	clock := NewManualClock(time.Unix(0, 0))
+	store := NewLeaseStore("test", clock)
 	store.Assign("c1", 3*time.Second, time.Second, 10, 12)
 	store.Assign("c2", 3*time.Second, time.Second, 10, 12)
 	store.Assign("c3", 5*time.Second, time.Second, 15, 20)
 
-	if want, got := 35, store.SumHas(); want != got {
+	if want, got := int32(35), store.SumHas(); want != got {
 		t.Errorf("store SumHas() %v want %v", got, want)
 	}
 
-	if want, got := 44, store.SumWant(); want != got {
+	if want, got := int32(44), store.SumWant(); want != got {
 		t.Errorf("store SumWant() %v want %v", got, want)
 	}
 
-	if want, got := 10, store.Get("c1").Has; want != got {
+	if want, got := int32(10), store.Get("c1").Has; want != got {
 		t.Errorf("store SumHas() %v want %v", got, want)
 	}
 
-	time.Sleep(3 * time.Second)
+	if want, got := int32(3), store.Count(); want != got {
+		t.Errorf("store Count() %v want %v", got, want)
+	}
+
+	// Leases are still valid at their expiry time.
+	clock.Advance(3 * time.Second)
 	store.Clean()
-	if want, got := 15, store.SumHas(); want != got {
+	if want, got := int32(3), store.Count(); want != got {
+		t.Errorf("store Count() %v want %v", got, want)
+	}
+
+	clock.Advance(time.Millisecond)
+	store.Clean()
+	if want, got := int32(15), store.SumHas(); want != got {
 		t.Errorf("store SumHas() %v want %v", got, want)
 	}
 
-	if want, got := 20, store.SumWant(); want != got {
+	if want, got := int32(20), store.SumWant(); want != got {
 		t.Errorf("store SumWant() %v want %v", got, want)
 	}
 
+	if want, got := int32(1), store.Count(); want != got {
+		t.Errorf("store Count() %v want %v", got, want)
+	}
+
 	if got := store.Get("c1"); !got.IsZero() {
 		t.Errorf("lease for client c1 is %v", got)
 	}
@@ -0,0 +1,382 @@
+package doorman
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/notfresh/zxdoorman/proto"
+	goproto "google.golang.org/protobuf/proto"
+)
+
+// maxTemplateConfigs bounds the number of derived configurations a
+// template caches, as clients choose the resource ids.
+const maxTemplateConfigs = 4096
+
+// captureRE matches the named captures of a template, like {shard},
+// and leadingCaptureRE those at the start of a text.
+var (
+	captureRE        = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+	leadingCaptureRE = regexp.MustCompile(`^` + captureRE.String())
+)
+
+// resourceTemplate is a resource configuration whose identifier glob has
+// named captures. A capture matches a non empty part of a resource id
+// without slashes; the rest of the template is matched as a glob with
+// * and ?.
+type resourceTemplate struct {
+	cfg      *proto.ResourcePB
+	re       *regexp.Regexp
+	captures []string
+	capacity expression
+
+	// configs caches the configurations derived by configFor by
+	// resource id, at most maxTemplateConfigs of them. Templates are
+	// compiled again by every load of the configuration, which
+	// empties it.
+	mu      sync.Mutex
+	configs map[string]*proto.ResourcePB
+}
+
+// isTemplate returns true if cfg is a template.
+func isTemplate(cfg *proto.ResourcePB) bool {
+	return captureRE.MatchString(cfg.GetIdentifierGlob())
+}
+
+// compileTemplate compiles the template configured by cfg.
+func compileTemplate(cfg *proto.ResourcePB) (*resourceTemplate, error) {
+	glob := cfg.GetIdentifierGlob()
+	t := &resourceTemplate{cfg: cfg, configs: make(map[string]*proto.ResourcePB)}
+	var pattern strings.Builder
+	pattern.WriteString("^")
+	last := 0
+	for _, m := range captureRE.FindAllStringSubmatchIndex(glob, -1) {
+		pattern.WriteString(globToRegexp(glob[last:m[0]]))
+		name := glob[m[2]:m[3]]
+		for _, c := range t.captures {
+			if c == name {
+				return nil, fmt.Errorf("capture {%v} appears twice", name)
+			}
+		}
+		t.captures = append(t.captures, name)
+		fmt.Fprintf(&pattern, "(?P<%v>[^/]+)", name)
+		last = m[1]
+	}
+	pattern.WriteString(globToRegexp(glob[last:]))
+	pattern.WriteString("$")
+	re, err := regexp.Compile(pattern.String())
+	if err != nil {
+		return nil, err
+	}
+	t.re = re
+
+	for _, o := range cfg.GetOverrides() {
+		if !t.hasCapture(o.GetCapture()) {
+			return nil, fmt.Errorf("override of unknown capture {%v}", o.GetCapture())
+		}
+		if _, err := filepath.Match(o.GetValue(), ""); err != nil {
+			return nil, fmt.Errorf("override of {%v}: %v", o.GetCapture(), err)
+		}
+	}
+	if text := cfg.GetCapacityExpression(); text != "" {
+		if t.capacity, err = parseExpression(text); err != nil {
+			return nil, fmt.Errorf("capacity expression %q: %v", text, err)
+		}
+		for _, name := range t.capacity.captures() {
+			if !t.hasCapture(name) {
+				return nil, fmt.Errorf("capacity expression %q: unknown capture {%v}", text, name)
+			}
+		}
+	}
+	return t, nil
+}
+
+// globToRegexp returns the regular expression matching what glob
+// matches, * and ? not matching slashes.
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	for _, r := range glob {
+		switch r {
+		case '*':
+			b.WriteString("[^/]*")
+		case '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	return b.String()
+}
+
+func (t *resourceTemplate) hasCapture(name string) bool {
+	for _, c := range t.captures {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+// match returns the values captured in id, or false if id does not
+// match the template.
+func (t *resourceTemplate) match(id string) (map[string]string, bool) {
+	m := t.re.FindStringSubmatch(id)
+	if m == nil {
+		return nil, false
+	}
+	values := make(map[string]string, len(t.captures))
+	for i, name := range t.re.SubexpNames() {
+		if name != "" {
+			values[name] = m[i]
+		}
+	}
+	return values, true
+}
+
+// configFor returns the configuration of the resource id, matching
+// the template with the values captured. Without overrides nor
+// capacity expression it is the template's configuration itself.
+func (t *resourceTemplate) configFor(id string, values map[string]string) *proto.ResourcePB {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if cfg, ok := t.configs[id]; ok {
+		return cfg
+	}
+	cfg := t.derive(id, values)
+	if cfg == t.cfg {
+		return cfg
+	}
+	if len(t.configs) >= maxTemplateConfigs {
+		// Forgets any of them: it is derived again if needed.
+		for other := range t.configs {
+			delete(t.configs, other)
+			break
+		}
+	}
+	t.configs[id] = cfg
+	return cfg
+}
+
+// derive does the work of configFor.
+func (t *resourceTemplate) derive(id string, values map[string]string) *proto.ResourcePB {
+	for _, o := range t.cfg.GetOverrides() {
+		if matched, _ := filepath.Match(o.GetValue(), values[o.GetCapture()]); matched {
+			cfg := goproto.Clone(t.cfg).(*proto.ResourcePB)
+			cfg.Capacity = o.GetCapacity()
+			if o.GetSafeCapacity() != 0 {
+				cfg.SafeCapacity = o.GetSafeCapacity()
+			}
+			return cfg
+		}
+	}
+	if t.capacity == nil {
+		return t.cfg
+	}
+	cfg := goproto.Clone(t.cfg).(*proto.ResourcePB)
+	capacity, err := t.capacity.eval(values)
+	if err != nil {
+		log.Printf("resource %v: %v, using capacity %v", id, err, cfg.GetCapacity())
+		return cfg
+	}
+	if math.IsNaN(capacity) || math.IsInf(capacity, 0) || capacity > math.MaxInt32 {
+		log.Printf("resource %v: capacity %v is out of range, using capacity %v", id, capacity, cfg.GetCapacity())
+		return cfg
+	}
+	if capacity < 0 {
+		capacity = 0
+	}
+	// Overrides are checked against the client floors when the
+	// configuration is loaded, expressions only here.
+	if floors := clientFloors(cfg); int32(capacity) < floors {
+		log.Printf("resource %v: capacity %v is less than the client floors %v, using capacity %v", id, int32(capacity), floors, cfg.GetCapacity())
+		return cfg
+	}
+	cfg.Capacity = int32(capacity)
+	return cfg
+}
+
+// compileTemplates compiles the templates of config, those of its
+// namespaces included, by configuration.
+func compileTemplates(config *proto.ResourceRepository) map[*proto.ResourcePB]*resourceTemplate {
+	templates := make(map[*proto.ResourcePB]*resourceTemplate)
+	resources := config.GetResources()
+	for _, ns := range config.GetNamespaces() {
+		resources = append(resources[:len(resources):len(resources)], ns.GetResources()...)
+	}
+	for _, cfg := range resources {
+		if !isTemplate(cfg) {
+			continue
+		}
+		t, err := compileTemplate(cfg)
+		if err != nil {
+			log.Printf("ignoring template %v: %v", cfg.GetIdentifierGlob(), err)
+			continue
+		}
+		templates[cfg] = t
+	}
+	return templates
+}
+
+// expression is an arithmetic expression of captured values.
+type expression interface {
+	eval(values map[string]string) (float64, error)
+	captures() []string
+}
+
+type number float64
+
+func (n number) eval(map[string]string) (float64, error) { return float64(n), nil }
+func (n number) captures() []string                      { return nil }
+
+type captureRef string
+
+func (c captureRef) eval(values map[string]string) (float64, error) {
+	v, err := strconv.ParseInt(values[string(c)], 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("{%v} is %q, not an integer", string(c), values[string(c)])
+	}
+	return float64(v), nil
+}
+
+func (c captureRef) captures() []string { return []string{string(c)} }
+
+type binary struct {
+	op          byte
+	left, right expression
+}
+
+func (b binary) eval(values map[string]string) (float64, error) {
+	l, err := b.left.eval(values)
+	if err != nil {
+		return 0, err
+	}
+	r, err := b.right.eval(values)
+	if err != nil {
+		return 0, err
+	}
+	switch b.op {
+	case '+':
+		return l + r, nil
+	case '-':
+		return l - r, nil
+	case '*':
+		return l * r, nil
+	}
+	if r == 0 {
+		return 0, fmt.Errorf("division by zero")
+	}
+	return l / r, nil
+}
+
+func (b binary) captures() []string {
+	return append(b.left.captures(), b.right.captures()...)
+}
+
+// parseExpression parses an expression of numbers, captures like
+// {shard}, the operators + - * / and parentheses.
+func parseExpression(text string) (expression, error) {
+	p := &exprParser{text: text}
+	e, err := p.sum()
+	if err != nil {
+		return nil, err
+	}
+	if p.skipSpace(); p.pos < len(p.text) {
+		return nil, fmt.Errorf("unexpected %q", p.text[p.pos:])
+	}
+	return e, nil
+}
+
+type exprParser struct {
+	text string
+	pos  int
+}
+
+func (p *exprParser) skipSpace() {
+	for p.pos < len(p.text) && unicode.IsSpace(rune(p.text[p.pos])) {
+		p.pos++
+	}
+}
+
+// peek returns the next character, or 0 at the end.
+func (p *exprParser) peek() byte {
+	p.skipSpace()
+	if p.pos == len(p.text) {
+		return 0
+	}
+	return p.text[p.pos]
+}
+
+func (p *exprParser) sum() (expression, error) {
+	left, err := p.product()
+	if err != nil {
+		return nil, err
+	}
+	for op := p.peek(); op == '+' || op == '-'; op = p.peek() {
+		p.pos++
+		right, err := p.product()
+		if err != nil {
+			return nil, err
+		}
+		left = binary{op, left, right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) product() (expression, error) {
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	for op := p.peek(); op == '*' || op == '/'; op = p.peek() {
+		p.pos++
+		right, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+		left = binary{op, left, right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) operand() (expression, error) {
+	switch c := p.peek(); {
+	case c == '(':
+		p.pos++
+		e, err := p.sum()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ')' {
+			return nil, fmt.Errorf("missing )")
+		}
+		p.pos++
+		return e, nil
+	case c == '{':
+		m := leadingCaptureRE.FindStringSubmatch(p.text[p.pos:])
+		if m == nil {
+			return nil, fmt.Errorf("bad capture at %q", p.text[p.pos:])
+		}
+		p.pos += len(m[0])
+		return captureRef(m[1]), nil
+	case c >= '0' && c <= '9' || c == '.':
+		start := p.pos
+		for p.pos < len(p.text) && (p.text[p.pos] >= '0' && p.text[p.pos] <= '9' || p.text[p.pos] == '.') {
+			p.pos++
+		}
+		v, err := strconv.ParseFloat(p.text[start:p.pos], 64)
+		if err != nil {
+			return nil, err
+		}
+		return number(v), nil
+	case c == 0:
+		return nil, fmt.Errorf("unexpected end")
+	default:
+		return nil, fmt.Errorf("unexpected %q", p.text[p.pos:])
+	}
+}
@@ -0,0 +1,212 @@
+package doorman
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/notfresh/zxdoorman/proto"
+	"golang.org/x/net/context"
+)
+
+func TestTemplateMatch(t *testing.T) {
+	tpl, err := compileTemplate(&proto.ResourcePB{IdentifierGlob: "db/{shard}/*.{op}"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		id     string
+		values map[string]string
+	}{
+		{"db/7/users.writes", map[string]string{"shard": "7", "op": "writes"}},
+		{"db/eu-1/x.reads", map[string]string{"shard": "eu-1", "op": "reads"}},
+		{"db//x.reads", nil},
+		{"db/1/2/x.reads", nil},
+		{"db/1/x", nil},
+	} {
+		values, ok := tpl.match(c.id)
+		if ok != (c.values != nil) {
+			t.Errorf("match(%q) = %v", c.id, ok)
+			continue
+		}
+		for k, v := range c.values {
+			if values[k] != v {
+				t.Errorf("match(%q)[%v] = %q want %q", c.id, k, values[k], v)
+			}
+		}
+	}
+}
+
+func TestParseExpression(t *testing.T) {
+	values := map[string]string{"shard": "3", "name": "big"}
+	for _, c := range []struct {
+		text string
+		want float64
+	}{
+		{"100", 100},
+		{"100 + 10 * {shard}", 130},
+		{"(100 + 10) * {shard}", 330},
+		{"90 / {shard} - 1.5", 28.5},
+	} {
+		e, err := parseExpression(c.text)
+		if err != nil {
+			t.Errorf("parseExpression(%q): %v", c.text, err)
+			continue
+		}
+		if got, err := e.eval(values); err != nil || got != c.want {
+			t.Errorf("%q = %v, %v want %v", c.text, got, err, c.want)
+		}
+	}
+	for _, bad := range []string{"", "1 +", "(1", "{shard", "1 2", "a"} {
+		if _, err := parseExpression(bad); err == nil {
+			t.Errorf("parseExpression(%q): no error", bad)
+		}
+	}
+	e, _ := parseExpression("{name} * 2")
+	if _, err := e.eval(values); err == nil {
+		t.Errorf("no error for a capture that is not a number")
+	}
+}
+
+func TestTemplateCapacities(t *testing.T) {
+	server, err := MakeTestServer(
+		&proto.ResourcePB{
+			IdentifierGlob:     "db/{shard}/writes",
+			Capacity:           100,
+			SafeCapacity:       10,
+			Algo:               &proto.AlgorithmPB{LeaseLength: 60},
+			CapacityExpression: "100 * {shard}",
+			Overrides: []*proto.CaptureOverridePB{
+				{Capture: "shard", Value: "0", Capacity: 5},
+				{Capture: "shard", Value: "9?", Capacity: 1000, SafeCapacity: 50},
+			},
+		},
+		&proto.ResourcePB{IdentifierGlob: "db/*/*", Capacity: 1},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	for _, c := range []struct {
+		id             string
+		capacity, safe int32
+	}{
+		{"db/2/writes", 200, 10},
+		{"db/0/writes", 5, 10},
+		{"db/97/writes", 1000, 50},
+		// Not an integer: the template's capacity.
+		{"db/main/writes", 100, 10},
+		{"db/inf/writes", 100, 10},
+		{"db/nan/writes", 100, 10},
+		{"db/1e12/writes", 100, 10},
+		{"db/2.5/writes", 100, 10},
+		// More than an int32: likewise.
+		{"db/30000000/writes", 100, 10},
+		{"db/2/reads", 1, 0},
+	} {
+		cfg := server.getOrCreateResource(c.id).Status().Config
+		if cfg.GetCapacity() != c.capacity || cfg.GetSafeCapacity() != c.safe {
+			t.Errorf("%v: capacity %v, safe capacity %v want %v, %v", c.id, cfg.GetCapacity(), cfg.GetSafeCapacity(), c.capacity, c.safe)
+		}
+	}
+}
+
+func TestValidateTemplates(t *testing.T) {
+	server, err := MakeTestServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	for _, cfg := range []*proto.ResourcePB{
+		{IdentifierGlob: "{a}/{a}"},
+		{IdentifierGlob: "{a}", Overrides: []*proto.CaptureOverridePB{{Capture: "b", Value: "1"}}},
+		{IdentifierGlob: "{a}", Overrides: []*proto.CaptureOverridePB{{Capture: "a", Value: "["}}},
+		{IdentifierGlob: "{a}", CapacityExpression: "10 *"},
+		{IdentifierGlob: "{a}", CapacityExpression: "{b}"},
+		{
+			IdentifierGlob: "{a}",
+			Overrides:      []*proto.CaptureOverridePB{{Capture: "a", Value: "1", Capacity: 5}},
+			ClientRules:    []*proto.ClientRulePB{{ClientGlob: "*", MinCapacity: 10}},
+		},
+	} {
+		cfg.Capacity = 100
+		err := server.LoadConfig(context.Background(), &proto.ResourceRepository{
+			Resources: []*proto.ResourcePB{cfg},
+		}, nil)
+		if err == nil {
+			t.Errorf("template %v: no error", cfg)
+		}
+	}
+}
+
+func TestTemplateFloors(t *testing.T) {
+	server, err := MakeTestServer(&proto.ResourcePB{
+		IdentifierGlob:     "db/{shard}",
+		Capacity:           100,
+		Algo:               &proto.AlgorithmPB{LeaseLength: 60},
+		CapacityExpression: "10 * {shard}",
+		ClientRules:        []*proto.ClientRulePB{{ClientGlob: "*", MinCapacity: 20}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	for _, c := range []struct {
+		id       string
+		capacity int32
+	}{
+		{"db/5", 50},
+		// Less than the floors: the template's capacity.
+		{"db/1", 100},
+	} {
+		if got := server.getOrCreateResource(c.id).Status().Config.GetCapacity(); got != c.capacity {
+			t.Errorf("%v: capacity %v want %v", c.id, got, c.capacity)
+		}
+	}
+}
+
+func TestTemplateConfigCache(t *testing.T) {
+	config := &proto.ResourceRepository{Resources: []*proto.ResourcePB{{
+		IdentifierGlob:     "db/{shard}",
+		Capacity:           100,
+		Algo:               &proto.AlgorithmPB{LeaseLength: 60},
+		CapacityExpression: "10 * {shard}",
+	}}}
+	server, err := MakeTestServer(config.Resources...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	find := func() *proto.ResourcePB {
+		server.mu.RLock()
+		defer server.mu.RUnlock()
+		return server.findConfigForResource("db/5")
+	}
+	cfg := find()
+	if find() != cfg {
+		t.Errorf("the configuration of db/5 was derived twice")
+	}
+	config.Resources[0].CapacityExpression = "20 * {shard}"
+	if err := server.LoadConfig(context.Background(), config, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := find().GetCapacity(); got != 100 {
+		t.Errorf("capacity %v after a new configuration, want 100", got)
+	}
+
+	tpl, err := compileTemplate(config.Resources[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < maxTemplateConfigs+10; i++ {
+		id := fmt.Sprint("db/", i)
+		values, _ := tpl.match(id)
+		tpl.configFor(id, values)
+	}
+	if n := len(tpl.configs); n > maxTemplateConfigs {
+		t.Errorf("the template caches %v configurations, more than %v", n, maxTemplateConfigs)
+	}
+}
@@ -30,11 +30,21 @@ func MakeTestServer(resources ...*pb.ResourcePB) (*Server, error) {
 	return MakeTestIntermediateServer("test", "", resources...)
 }
 
+// MakeTestServerWithClock creates a test root server whose time is
+// told by clock.
+func MakeTestServerWithClock(clock Clock, resources ...*pb.ResourcePB) (*Server, error) {
+	return makeTestServer("test", "", []ServerOption{WithClock(clock)}, resources)
+}
+
 // MakeTestIntermediateServer creates a test intermediate server with
 // specified name and connected to the lower-level server with address addr.
 func MakeTestIntermediateServer(name string, addr string, resources ...*pb.ResourcePB) (*Server, error) {
+	return makeTestServer(name, addr, nil, resources)
+}
+
+func makeTestServer(name string, addr string, opts []ServerOption, resources []*pb.ResourcePB) (*Server, error) {
 	// Creates a new test server that is the master.
-	server, err := NewServer(context.Background(), name)
+	server, err := NewServer(context.Background(), name, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("server.NewIntermediate: %v", err)
 	}
@@ -0,0 +1,124 @@
+package doorman
+
+import (
+	"time"
+
+	"github.com/notfresh/zxdoorman/proto"
+)
+
+// WatchCapacity assigns capacity leases to a client and keeps
+// sending them, whenever the client's share changes and before they
+// need renewing, until the stream is closed. The leases are renewed
+// only while the stream is open: if it breaks they expire as if the
+// client had stopped polling. It is part of the
+// doorman.CapacityServer implementation.
+func (server *Server) WatchCapacity(in *proto.GetCapacityRequest, stream proto.Capacity_WatchCapacityServer) (err error) {
+	defer func(start time.Time) {
+		server.metrics.observeRequest("WatchCapacity", start, err)
+	}(time.Now())
+
+	requests := clientRequests(in)
+	wake := make(chan struct{}, 1)
+	var (
+		last    *proto.GetCapacityResponse
+		renewAt time.Time
+	)
+	// One timer for the whole watch, as changes can wake the loop
+	// far more often than leases need renewing.
+	timer := server.clock.NewTimer(defaultInterval)
+	defer timer.Stop()
+
+	for {
+		// The configuration may have changed since the last time.
+		if err := server.checkAccess(requests); err != nil {
+			return err
+		}
+
+		// Watches before deciding, so that no change is missed.
+		var watched []*Resource
+		for _, req := range requests {
+			res := server.getOrCreateResource(req.resID)
+			res.watch(wake)
+			watched = append(watched, res)
+		}
+
+		out := server.decide(requests)
+		// What the client has now is what it was just given.
+		has := make(map[string]int32)
+		for _, resp := range out.Response {
+			has[resp.GetResourceId()] = resp.GetGets().GetCapacity()
+		}
+		for i := range requests {
+			requests[i].has = has[requests[i].resID]
+		}
+
+		now := server.clock.Now()
+		if !now.Before(renewAt) || sharesChanged(last, out) {
+			if err := stream.Send(out); err != nil {
+				unwatchAll(watched, wake)
+				return err
+			}
+			last = out
+			renewAt = now.Add(renewInterval(out, now))
+		}
+
+		timer.Reset(renewAt.Sub(now))
+		select {
+		case <-stream.Context().Done():
+			err = stream.Context().Err()
+		case <-wake:
+		case <-timer.C():
+		}
+		unwatchAll(watched, wake)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func unwatchAll(resources []*Resource, wake chan struct{}) {
+	for _, res := range resources {
+		res.unwatch(wake)
+	}
+}
+
+// sharesChanged returns true if the capacities in out differ from
+// those in last.
+func sharesChanged(last, out *proto.GetCapacityResponse) bool {
+	if last == nil || len(last.Response) != len(out.Response) {
+		return true
+	}
+	capacities := make(map[string]*proto.GetCapacityResponse_ResourceResponse)
+	for _, resp := range last.Response {
+		capacities[resp.GetResourceId()] = resp
+	}
+	for _, resp := range out.Response {
+		prev, ok := capacities[resp.GetResourceId()]
+		if !ok || prev.GetGets().GetCapacity() != resp.GetGets().GetCapacity() ||
+			prev.GetSafeCapacity() != resp.GetSafeCapacity() ||
+			prev.GetExpired() != resp.GetExpired() {
+			return true
+		}
+	}
+	return false
+}
+
+// renewInterval returns how long after now the leases in out need
+// renewing: the shortest refresh interval, or half the time to the
+// earliest expiry if no refresh interval is configured.
+func renewInterval(out *proto.GetCapacityResponse, now time.Time) time.Duration {
+	interval := time.Duration(0)
+	for _, resp := range out.Response {
+		d := time.Duration(resp.GetGets().GetRefreshInterval()) * time.Second
+		if d <= 0 {
+			d = time.Unix(resp.GetGets().GetExpiryTime(), 0).Sub(now) / 2
+		}
+		if interval == 0 || (d > 0 && d < interval) {
+			interval = d
+		}
+	}
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	return interval
+}
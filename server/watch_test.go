@@ -0,0 +1,129 @@
+package doorman
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/notfresh/zxdoorman/proto"
+	"golang.org/x/net/context"
+	rpc "google.golang.org/grpc"
+)
+
+// serve serves server over gRPC on a local port and returns a client
+// connected to it with dialOpts.
+func serve(t *testing.T, server *Server, serverOpts []rpc.ServerOption, dialOpts ...rpc.DialOption) (proto.CapacityClient, func()) {
+	t.Helper()
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rpcServer := rpc.NewServer(serverOpts...)
+	proto.RegisterCapacityServer(rpcServer, server)
+	go rpcServer.Serve(lis)
+
+	conn, err := rpc.Dial(lis.Addr().String(), append(dialOpts, rpc.WithInsecure())...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return proto.NewCapacityClient(conn), func() {
+		conn.Close()
+		rpcServer.Stop()
+	}
+}
+
+func TestWatchCapacity(t *testing.T) {
+	clock := NewManualClock(time.Unix(1000000, 0))
+	server, err := MakeTestServerWithClock(clock, &proto.ResourcePB{
+		IdentifierGlob: "*",
+		Capacity:       100,
+		Algo:           &proto.AlgorithmPB{LeaseLength: 60, RefreshInterval: 10},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	// Gets out of learning mode.
+	clock.Advance(2 * time.Minute)
+
+	client, stop := serve(t, server, nil)
+	defer stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	stream, err := client.WatchCapacity(ctx, &proto.GetCapacityRequest{
+		ClientId: "watcher",
+		Resource: []*proto.GetCapacityRequest_ResourceRequest{
+			{ResourceId: "res", Want: 30},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// waitFor receives leases until one has the wanted safe capacity.
+	waitFor := func(safeCapacity int32) {
+		t.Helper()
+		for {
+			out, err := stream.Recv()
+			if err != nil {
+				t.Fatalf("waiting for safe capacity %v: %v", safeCapacity, err)
+			}
+			resp := out.GetResponse()[0]
+			if resp.GetGets().GetCapacity() != 30 {
+				t.Errorf("watcher got capacity %v want 30", resp.GetGets().GetCapacity())
+			}
+			if resp.GetSafeCapacity() == safeCapacity {
+				return
+			}
+		}
+	}
+	waitFor(100)
+
+	// Another client halves the watcher's share.
+	getCapacity(t, server, "other", "res", 0, 10)
+	waitFor(50)
+
+	// So does a configuration change.
+	if err := server.LoadConfig(context.Background(), repositoryWithCapacity(200), nil); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(100)
+
+	// The wakes reused the watch's timer: the only other one pending
+	// is the run loop's.
+	clock.mu.Lock()
+	n := len(clock.timers)
+	clock.mu.Unlock()
+	if n > 2 {
+		t.Errorf("%v timers pending after 3 wakes, want at most 2", n)
+	}
+
+	// The other client goes away: its lease expires while the
+	// watcher's is renewed.
+	clock.Advance(61 * time.Second)
+	waitFor(200)
+
+	// Once the stream is closed the watcher's lease expires too.
+	cancel()
+	res := server.getOrCreateResource("res")
+	for i := 0; ; i++ {
+		res.mu.RLock()
+		n := len(res.watchers)
+		res.mu.RUnlock()
+		if n == 0 {
+			break
+		}
+		if i == 1000 {
+			t.Fatalf("the watcher did not stop")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if want, got := int32(1), res.Status().Count; want != got {
+		t.Errorf("%v clients right after the stream closed, want %v", got, want)
+	}
+	clock.Advance(61 * time.Second)
+	if want, got := int32(0), res.Status().Count; want != got {
+		t.Errorf("%v clients after the lease length, want %v", got, want)
+	}
+}
@@ -0,0 +1,197 @@
+// Package tlsconfig builds the TLS configurations used by doorman
+// servers and clients. Certificates, keys and CA bundles are read from
+// files, which are reloaded when they change so that certificates can
+// be rotated without restarting.
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// fileState is what tells whether a file changed since it was read.
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+func stat(path string) (fileState, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return fileState{}, err
+	}
+	return fileState{fi.ModTime(), fi.Size()}, nil
+}
+
+// keyPair is a certificate and its key, reloaded from their files
+// when either changes.
+type keyPair struct {
+	certFile, keyFile string
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	certState fileState
+	keyState  fileState
+}
+
+func newKeyPair(certFile, keyFile string) (*keyPair, error) {
+	kp := &keyPair{certFile: certFile, keyFile: keyFile}
+	if _, err := kp.get(); err != nil {
+		return nil, err
+	}
+	return kp, nil
+}
+
+// get returns the current certificate. If the files changed but
+// cannot be loaded, for example because only one of them was
+// rewritten yet, it keeps returning the previous one.
+func (kp *keyPair) get() (*tls.Certificate, error) {
+	kp.mu.Lock()
+	defer kp.mu.Unlock()
+
+	certState, certErr := stat(kp.certFile)
+	keyState, keyErr := stat(kp.keyFile)
+	if kp.cert != nil && certErr == nil && keyErr == nil &&
+		certState == kp.certState && keyState == kp.keyState {
+		return kp.cert, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(kp.certFile, kp.keyFile)
+	if err != nil {
+		if kp.cert != nil {
+			log.Printf("cannot reload certificate %v: %v", kp.certFile, err)
+			return kp.cert, nil
+		}
+		return nil, err
+	}
+	kp.cert, kp.certState, kp.keyState = &cert, certState, keyState
+	return kp.cert, nil
+}
+
+// certPool is a pool of CA certificates, reloaded from its file when
+// it changes.
+type certPool struct {
+	file string
+
+	mu    sync.Mutex
+	pool  *x509.CertPool
+	state fileState
+}
+
+func newCertPool(file string) (*certPool, error) {
+	cp := &certPool{file: file}
+	if _, err := cp.get(); err != nil {
+		return nil, err
+	}
+	return cp, nil
+}
+
+// get returns the current pool, keeping the previous one if the
+// file changed but cannot be loaded.
+func (cp *certPool) get() (*x509.CertPool, error) {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	state, err := stat(cp.file)
+	if cp.pool != nil && err == nil && state == cp.state {
+		return cp.pool, nil
+	}
+
+	pool, err := loadCertPool(cp.file)
+	if err != nil {
+		if cp.pool != nil {
+			log.Printf("cannot reload CA bundle %v: %v", cp.file, err)
+			return cp.pool, nil
+		}
+		return nil, err
+	}
+	cp.pool, cp.state = pool, state
+	return cp.pool, nil
+}
+
+func loadCertPool(file string) (*x509.CertPool, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates found in %v", file)
+	}
+	return pool, nil
+}
+
+// Server returns the TLS configuration of a server presenting the
+// certificate in certFile with the key in keyFile. If clientCAFile is
+// not empty clients must present a certificate signed by one of the
+// CAs in it. All the files are reloaded when they change.
+func Server(certFile, keyFile, clientCAFile string) (*tls.Config, error) {
+	kp, err := newKeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	var clientCAs *certPool
+	if clientCAFile != "" {
+		if clientCAs, err = newCertPool(clientCAFile); err != nil {
+			return nil, err
+		}
+	}
+
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			cert, err := kp.get()
+			if err != nil {
+				return nil, err
+			}
+			config := &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*cert},
+			}
+			if clientCAs != nil {
+				pool, err := clientCAs.get()
+				if err != nil {
+					return nil, err
+				}
+				config.ClientCAs = pool
+				config.ClientAuth = tls.RequireAndVerifyClientCert
+			}
+			return config, nil
+		},
+	}, nil
+}
+
+// Client returns the TLS configuration of a client verifying servers
+// against the CAs in caFile, or the system's CAs if it is empty.
+// serverName overrides the name expected in the server's certificate.
+// If certFile is not empty the client presents the certificate in it,
+// with the key in keyFile, reloading them when they change.
+func Client(caFile, certFile, keyFile, serverName string) (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: serverName,
+	}
+	if caFile != "" {
+		pool, err := loadCertPool(caFile)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = pool
+	}
+	if certFile != "" {
+		kp, err := newKeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return kp.get()
+		}
+	}
+	return config, nil
+}